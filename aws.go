@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const defaultRoleSessionName = "drone-lambda"
+
+// webIdentityToken returns an OIDC token that was passed in directly,
+// e.g. from an environment variable populated by the CI system.
+type webIdentityToken string
+
+// FetchToken implements stscreds.TokenFetcher.
+func (t webIdentityToken) FetchToken(credentials.Context) ([]byte, error) {
+	return []byte(strings.TrimSpace(string(t))), nil
+}
+
+// tokenFetcher returns the configured source of the web identity token,
+// or nil if web identity federation is not configured.
+func (p Plugin) tokenFetcher() stscreds.TokenFetcher {
+	switch {
+	case p.Config.WebIdentityToken != "":
+		return webIdentityToken(p.Config.WebIdentityToken)
+	case p.Config.WebIdentityTokenFile != "":
+		return stscreds.FetchTokenPath(p.Config.WebIdentityTokenFile)
+	}
+
+	return nil
+}
+
+func (p Plugin) validateCredentials() error {
+	fetcher := p.tokenFetcher()
+	if fetcher != nil && p.Config.RoleARN == "" {
+		return configError("missing role arn for web identity token")
+	}
+	if fetcher == nil && p.Config.RoleARN != "" &&
+		p.Config.AccessKey == "" && p.Config.Profile == "" {
+		return configError("missing web identity token or token file for role arn")
+	}
+
+	return nil
+}
+
+// newSession creates the AWS session shared by every service client.
+// Credentials are resolved in the following order: static keys, web
+// identity token, shared profile and the SDK default chain.
+func (p Plugin) newSession() (*session.Session, error) {
+	if err := p.validateCredentials(); err != nil {
+		return nil, err
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	config := &aws.Config{
+		Region: aws.String(p.Config.Region),
+	}
+
+	switch {
+	case p.Config.AccessKey != "" && p.Config.SecretKey != "":
+		config.Credentials = credentials.NewStaticCredentials(
+			p.Config.AccessKey,
+			p.Config.SecretKey,
+			p.Config.SessionToken,
+		)
+	case p.tokenFetcher() != nil:
+		sessionName := p.Config.RoleSessionName
+		if sessionName == "" {
+			sessionName = defaultRoleSessionName
+		}
+		// AssumeRoleWithWebIdentity is an unsigned call, so the STS client
+		// does not need any credentials of its own.
+		svc := sts.New(sess, &aws.Config{
+			Region:      aws.String(p.Config.Region),
+			Credentials: credentials.AnonymousCredentials,
+		})
+		config.Credentials = credentials.NewCredentials(
+			stscreds.NewWebIdentityRoleProviderWithOptions(
+				svc,
+				p.Config.RoleARN,
+				sessionName,
+				p.tokenFetcher(),
+			),
+		)
+	case p.Config.Profile != "":
+		config.Credentials = credentials.NewSharedCredentials("", p.Config.Profile)
+	}
+
+	return sess.Copy(config), nil
+}
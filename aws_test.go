@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlugin_tokenFetcher(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("file-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{
+			name:   "none",
+			config: Config{},
+		},
+		{
+			name:   "token",
+			config: Config{WebIdentityToken: " env-token\n"},
+			want:   "env-token",
+		},
+		{
+			name:   "file",
+			config: Config{WebIdentityTokenFile: file},
+			want:   "file-token",
+		},
+		{
+			name:   "token takes precedence",
+			config: Config{WebIdentityToken: "env-token", WebIdentityTokenFile: file},
+			want:   "env-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := Plugin{Config: tt.config}.tokenFetcher()
+			if tt.want == "" {
+				if fetcher != nil {
+					t.Fatalf("tokenFetcher() = %v, want nil", fetcher)
+				}
+				return
+			}
+
+			got, err := fetcher.FetchToken(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("FetchToken() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlugin_validateCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{
+			name:   "default chain",
+			config: Config{},
+		},
+		{
+			name:   "web identity",
+			config: Config{RoleARN: "arn:aws:iam::123456789012:role/deploy", WebIdentityToken: "token"},
+		},
+		{
+			name:    "token without role",
+			config:  Config{WebIdentityTokenFile: "/var/run/token"},
+			wantErr: true,
+		},
+		{
+			name:    "role without token",
+			config:  Config{RoleARN: "arn:aws:iam::123456789012:role/deploy"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Plugin{Config: tt.config}.validateCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
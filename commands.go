@@ -0,0 +1,172 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// commands returns the subcommands. Running the app without a command
+// deploys the function.
+func commands() []*cli.Command {
+	return []*cli.Command{
+		invokeCommand(),
+		logsCommand(),
+		exportCommand(),
+		downloadCommand(),
+		packageCommand(),
+		throttleCommand(),
+		unthrottleCommand(),
+	}
+}
+
+// awsFlags returns the region, credential and logging flags shared by the
+// deploy and every subcommand that calls AWS. Each call returns new flags,
+// so they can be added to more than one command.
+func awsFlags() []cli.Flag {
+	return append([]cli.Flag{
+		&cli.StringFlag{
+			Name:    "region",
+			Usage:   "AWS Region",
+			EnvVars: []string{"PLUGIN_REGION", "PLUGIN_AWS_REGION", "INPUT_AWS_REGION"},
+		},
+		&cli.StringFlag{
+			Name:  "access-key",
+			Usage: "AWS ACCESS KEY",
+			EnvVars: []string{
+				"PLUGIN_ACCESS_KEY",
+				"PLUGIN_AWS_ACCESS_KEY_ID",
+				"INPUT_AWS_ACCESS_KEY_ID",
+			},
+		},
+		&cli.StringFlag{
+			Name:  "secret-key",
+			Usage: "AWS SECRET KEY",
+			EnvVars: []string{
+				"PLUGIN_SECRET_KEY",
+				"PLUGIN_AWS_SECRET_ACCESS_KEY",
+				"INPUT_AWS_SECRET_ACCESS_KEY",
+			},
+		},
+		&cli.StringFlag{
+			Name:  "session-token",
+			Usage: "AWS Session token",
+			EnvVars: []string{
+				"PLUGIN_SESSION_TOKEN",
+				"PLUGIN_AWS_SESSION_TOKEN",
+				"INPUT_AWS_SESSION_TOKEN",
+			},
+		},
+		&cli.StringFlag{
+			Name:  "role-arn",
+			Usage: "AWS IAM role to assume with the web identity token",
+			EnvVars: []string{
+				"PLUGIN_ROLE_ARN",
+				"PLUGIN_AWS_ROLE_ARN",
+				"INPUT_AWS_ROLE_ARN",
+				"AWS_ROLE_ARN",
+			},
+		},
+		&cli.StringFlag{
+			Name:  "role-session-name",
+			Usage: "AWS session name used when assuming the role",
+			Value: defaultRoleSessionName,
+			EnvVars: []string{
+				"PLUGIN_ROLE_SESSION_NAME",
+				"PLUGIN_AWS_ROLE_SESSION_NAME",
+				"INPUT_AWS_ROLE_SESSION_NAME",
+				"AWS_ROLE_SESSION_NAME",
+			},
+		},
+		&cli.StringFlag{
+			Name:  "web-identity-token",
+			Usage: "OIDC token issued by the CI system",
+			EnvVars: []string{
+				"PLUGIN_WEB_IDENTITY_TOKEN",
+				"PLUGIN_AWS_WEB_IDENTITY_TOKEN",
+				"INPUT_AWS_WEB_IDENTITY_TOKEN",
+			},
+		},
+		&cli.StringFlag{
+			Name:  "web-identity-token-file",
+			Usage: "path to a file containing the OIDC token issued by the CI system",
+			EnvVars: []string{
+				"PLUGIN_WEB_IDENTITY_TOKEN_FILE",
+				"PLUGIN_AWS_WEB_IDENTITY_TOKEN_FILE",
+				"INPUT_AWS_WEB_IDENTITY_TOKEN_FILE",
+				"AWS_WEB_IDENTITY_TOKEN_FILE",
+			},
+		},
+		&cli.StringFlag{
+			Name:    "aws-profile",
+			Usage:   "AWS profile",
+			EnvVars: []string{"PLUGIN_PROFILE", "PLUGIN_AWS_PROFILE", "INPUT_AWS_PROFILE"},
+		},
+	}, logFlags()...)
+}
+
+// logFlags returns the logging flags shared by every command.
+func logFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "debug",
+			Usage:   "Show debug message after upload the lambda successfully.",
+			EnvVars: []string{"PLUGIN_DEBUG", "DEBUG", "INPUT_DEBUG"},
+		},
+		&cli.StringFlag{
+			Name:    "log-format",
+			Usage:   "The format of the log output, text or json.",
+			Value:   LogFormatText,
+			EnvVars: []string{"PLUGIN_LOG_FORMAT", "LOG_FORMAT", "INPUT_LOG_FORMAT"},
+		},
+	}
+}
+
+// functionNameFlag returns the function name flag of the subcommands.
+func functionNameFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "function-name",
+		Usage:   "AWS lambda function name",
+		EnvVars: []string{"PLUGIN_FUNCTION_NAME", "FUNCTION_NAME", "INPUT_FUNCTION_NAME"},
+	}
+}
+
+func setupLogger(c *cli.Context) (*slog.Logger, error) {
+	logger, err := newLogger(os.Stderr, c.String("log-format"), c.Bool("debug"))
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+
+	return logger, nil
+}
+
+// commandPlugin returns a plugin configured from the flags shared by the
+// subcommands, so they resolve credentials and region like the deploy.
+func commandPlugin(c *cli.Context) (*Plugin, error) {
+	logger, err := setupLogger(c)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plugin{
+		Config: Config{
+			Region:               c.String("region"),
+			AccessKey:            c.String("access-key"),
+			SecretKey:            c.String("secret-key"),
+			SessionToken:         c.String("session-token"),
+			Profile:              c.String("aws-profile"),
+			RoleARN:              c.String("role-arn"),
+			RoleSessionName:      c.String("role-session-name"),
+			WebIdentityToken:     c.String("web-identity-token"),
+			WebIdentityTokenFile: c.String("web-identity-token-file"),
+			FunctionName:         c.String("function-name"),
+			Debug:                c.Bool("debug"),
+			LogFormat:            c.String("log-format"),
+		},
+		log: logger.With("version", Version),
+	}
+
+	return p, nil
+}
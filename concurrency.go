@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// provisionedPollInterval is the delay between two polls while waiting
+// for provisioned concurrency to be allocated.
+var provisionedPollInterval = 5 * time.Second
+
+func (p Plugin) validateConcurrency() error {
+	reserved := p.Config.ReservedConcurrency
+	if reserved != nil && *reserved < 0 {
+		return configError("reserved concurrency must not be negative")
+	}
+	if p.Config.ProvisionedConcurrency < 0 {
+		return configError("provisioned concurrency must not be negative")
+	}
+	if reserved != nil && p.Config.ProvisionedConcurrency > *reserved {
+		return configError("provisioned concurrency %d exceeds reserved concurrency %d",
+			p.Config.ProvisionedConcurrency, *reserved)
+	}
+
+	return nil
+}
+
+// putReservedConcurrency sets or removes the reserved concurrency of the
+// function. It applies to every version, so it is not tied to the
+// revision of the code update. A throttled function stays throttled and
+// the value is saved for unthrottle instead.
+func (p *Plugin) putReservedConcurrency(ctx context.Context, svc lambdaiface.LambdaAPI) error {
+	reserved := p.Config.ReservedConcurrency
+	remove := p.clears(ClearReservedConcurrency)
+	if (reserved == nil && !remove) || p.Config.DryRun {
+		return nil
+	}
+	if remove {
+		reserved = nil
+	}
+
+	ctx, done := p.phase(ctx, "concurrency", "put reserved concurrency")
+	throttled, err := p.saveThrottled(ctx, svc, reserved)
+	if err == nil && !throttled {
+		if remove {
+			err = p.retry(ctx, svc, "delete function concurrency", func() error {
+				_, err := svc.DeleteFunctionConcurrencyWithContext(ctx, &lambda.DeleteFunctionConcurrencyInput{
+					FunctionName: aws.String(p.Config.FunctionName),
+				})
+				return err
+			})
+		} else {
+			err = p.retry(ctx, svc, "put function concurrency", func() error {
+				_, err := svc.PutFunctionConcurrencyWithContext(ctx, &lambda.PutFunctionConcurrencyInput{
+					FunctionName:                 aws.String(p.Config.FunctionName),
+					ReservedConcurrentExecutions: reserved,
+				})
+				return err
+			})
+		}
+	}
+	done(err)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case throttled:
+		p.logger().Warn("function is throttled, the reserved concurrency is saved for unthrottle",
+			"phase", "concurrency", "reserved", throttleValue(reserved))
+	case remove:
+		p.logger().Info("reserved concurrency removed", "phase", "concurrency")
+	default:
+		p.logger().Info("reserved concurrency set", "phase", "concurrency", "reserved", *reserved)
+	}
+	return nil
+}
+
+// moveProvisionedConcurrency allocates provisioned concurrency on the
+// published version and, once it is ready, removes it from the older
+// versions that no alias points to, since the deploy does not move
+// aliases. Provisioned concurrency on aliases is left alone.
+func (p *Plugin) moveProvisionedConcurrency(ctx context.Context, svc lambdaiface.LambdaAPI, version string) error {
+	ctx, done := p.phase(ctx, "provisioned", "put provisioned concurrency")
+	err := p.putProvisionedConcurrency(ctx, svc, version)
+	if err == nil {
+		err = p.removeProvisionedConcurrency(ctx, svc, version)
+	}
+	done(err)
+
+	return err
+}
+
+func (p *Plugin) putProvisionedConcurrency(ctx context.Context, svc lambdaiface.LambdaAPI, version string) error {
+	logger := p.logger().With("phase", "provisioned", "function_version", version)
+	_, err := svc.PutProvisionedConcurrencyConfigWithContext(ctx, &lambda.PutProvisionedConcurrencyConfigInput{
+		FunctionName:                    aws.String(p.Config.FunctionName),
+		Qualifier:                       aws.String(version),
+		ProvisionedConcurrentExecutions: aws.Int64(p.Config.ProvisionedConcurrency),
+	})
+	if err != nil {
+		return wrapError("put provisioned concurrency", err)
+	}
+
+	start := time.Now()
+	defer func() { p.waited += time.Since(start) }()
+	for attempt := 1; ; attempt++ {
+		c, err := svc.GetProvisionedConcurrencyConfigWithContext(ctx, &lambda.GetProvisionedConcurrencyConfigInput{
+			FunctionName: aws.String(p.Config.FunctionName),
+			Qualifier:    aws.String(version),
+		})
+		if err != nil {
+			return wrapError("get provisioned concurrency", err)
+		}
+
+		status := aws.StringValue(c.Status)
+		logger.Info("waiting for provisioned concurrency",
+			"status", status,
+			"available", aws.Int64Value(c.AvailableProvisionedConcurrentExecutions),
+			"requested", aws.Int64Value(c.RequestedProvisionedConcurrentExecutions),
+			"attempt", attempt,
+			"max_attempts", p.Config.MaxAttempts,
+		)
+		switch status {
+		case lambda.ProvisionedConcurrencyStatusEnumReady:
+			return nil
+		case lambda.ProvisionedConcurrencyStatusEnumFailed:
+			return &Error{
+				Kind: KindUnknown,
+				Op:   "put provisioned concurrency",
+				Err:  fmt.Errorf("provisioned concurrency of version %s failed", version),
+				Hint: aws.StringValue(c.StatusReason),
+			}
+		}
+
+		if attempt >= p.Config.MaxAttempts {
+			return &Error{
+				Kind: KindWaiterTimeout,
+				Op:   "put provisioned concurrency",
+				Err:  fmt.Errorf("provisioned concurrency of version %s is %s", version, status),
+				Hint: "raise max attempts or check the provisioned concurrency in the console",
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return wrapError("put provisioned concurrency", ctx.Err())
+		case <-time.After(provisionedPollInterval):
+		}
+	}
+}
+
+func (p *Plugin) removeProvisionedConcurrency(ctx context.Context, svc lambdaiface.LambdaAPI, version string) error {
+	aliased, err := aliasedVersions(ctx, svc, p.Config.FunctionName)
+	if err != nil {
+		return err
+	}
+
+	var versions []string
+	if err := svc.ListProvisionedConcurrencyConfigsPagesWithContext(ctx,
+		&lambda.ListProvisionedConcurrencyConfigsInput{FunctionName: aws.String(p.Config.FunctionName)},
+		func(page *lambda.ListProvisionedConcurrencyConfigsOutput, _ bool) bool {
+			for _, pc := range page.ProvisionedConcurrencyConfigs {
+				v, ok := qualifiedVersion(aws.StringValue(pc.FunctionArn))
+				if !ok || v == version {
+					continue
+				}
+				if aliased[v] {
+					p.logger().Info("provisioned concurrency kept, an alias points to the version",
+						"phase", "provisioned", "function_version", v)
+					continue
+				}
+				versions = append(versions, v)
+			}
+			return true
+		}); err != nil {
+		return wrapError("list provisioned concurrency", err)
+	}
+
+	for _, v := range versions {
+		if _, err := svc.DeleteProvisionedConcurrencyConfigWithContext(ctx,
+			&lambda.DeleteProvisionedConcurrencyConfigInput{
+				FunctionName: aws.String(p.Config.FunctionName),
+				Qualifier:    aws.String(v),
+			}); err != nil {
+			return wrapError("delete provisioned concurrency", err)
+		}
+		p.logger().Info("provisioned concurrency removed", "phase", "provisioned", "function_version", v)
+	}
+
+	return nil
+}
+
+// aliasedVersions returns the versions that an alias points to or routes
+// traffic to.
+func aliasedVersions(ctx context.Context, svc lambdaiface.LambdaAPI, name string) (map[string]bool, error) {
+	versions := make(map[string]bool)
+	if err := svc.ListAliasesPagesWithContext(ctx,
+		&lambda.ListAliasesInput{FunctionName: aws.String(name)},
+		func(page *lambda.ListAliasesOutput, _ bool) bool {
+			for _, alias := range page.Aliases {
+				versions[aws.StringValue(alias.FunctionVersion)] = true
+				if alias.RoutingConfig != nil {
+					for v := range alias.RoutingConfig.AdditionalVersionWeights {
+						versions[v] = true
+					}
+				}
+			}
+			return true
+		}); err != nil {
+		return nil, wrapError("list aliases", err)
+	}
+
+	return versions, nil
+}
+
+// qualifiedVersion returns the version of a qualified function arn, or
+// false if the arn is qualified with an alias.
+func qualifiedVersion(arn string) (string, bool) {
+	// arn:aws:lambda:region:account:function:name:qualifier
+	parts := strings.Split(arn, ":")
+	if len(parts) != 8 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(parts[7], 10, 64); err != nil {
+		return "", false
+	}
+	return parts[7], true
+}
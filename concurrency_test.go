@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func (f *fakeLambda) PutFunctionConcurrencyWithContext(
+	_ aws.Context, input *lambda.PutFunctionConcurrencyInput, _ ...request.Option,
+) (*lambda.PutFunctionConcurrencyOutput, error) {
+	f.calls = append(f.calls, "PutFunctionConcurrency")
+	f.reserved = input.ReservedConcurrentExecutions
+	return &lambda.PutFunctionConcurrencyOutput{ReservedConcurrentExecutions: f.reserved}, nil
+}
+
+func (f *fakeLambda) DeleteFunctionConcurrencyWithContext(
+	aws.Context, *lambda.DeleteFunctionConcurrencyInput, ...request.Option,
+) (*lambda.DeleteFunctionConcurrencyOutput, error) {
+	f.calls = append(f.calls, "DeleteFunctionConcurrency")
+	f.reserved = nil
+	return &lambda.DeleteFunctionConcurrencyOutput{}, nil
+}
+
+func (f *fakeLambda) qualifiedArn(qualifier string) string {
+	return aws.StringValue(f.config.FunctionArn) + ":" + qualifier
+}
+
+func (f *fakeLambda) PutProvisionedConcurrencyConfigWithContext(
+	_ aws.Context, input *lambda.PutProvisionedConcurrencyConfigInput, _ ...request.Option,
+) (*lambda.PutProvisionedConcurrencyConfigOutput, error) {
+	f.calls = append(f.calls, "PutProvisionedConcurrencyConfig:"+aws.StringValue(input.Qualifier))
+	f.provisioned = append(f.provisioned, &lambda.ProvisionedConcurrencyConfigListItem{
+		FunctionArn:                              aws.String(f.qualifiedArn(aws.StringValue(input.Qualifier))),
+		RequestedProvisionedConcurrentExecutions: input.ProvisionedConcurrentExecutions,
+	})
+	return &lambda.PutProvisionedConcurrencyConfigOutput{
+		Status: aws.String(lambda.ProvisionedConcurrencyStatusEnumInProgress),
+	}, nil
+}
+
+func (f *fakeLambda) GetProvisionedConcurrencyConfigWithContext(
+	aws.Context, *lambda.GetProvisionedConcurrencyConfigInput, ...request.Option,
+) (*lambda.GetProvisionedConcurrencyConfigOutput, error) {
+	status := f.provisionedStatus[0]
+	if len(f.provisionedStatus) > 1 {
+		f.provisionedStatus = f.provisionedStatus[1:]
+	}
+	return &lambda.GetProvisionedConcurrencyConfigOutput{
+		Status:       aws.String(status),
+		StatusReason: aws.String(f.provisionedReason),
+	}, nil
+}
+
+func (f *fakeLambda) DeleteProvisionedConcurrencyConfigWithContext(
+	_ aws.Context, input *lambda.DeleteProvisionedConcurrencyConfigInput, _ ...request.Option,
+) (*lambda.DeleteProvisionedConcurrencyConfigOutput, error) {
+	f.calls = append(f.calls, "DeleteProvisionedConcurrencyConfig:"+aws.StringValue(input.Qualifier))
+	arn := f.qualifiedArn(aws.StringValue(input.Qualifier))
+	f.provisioned = slices.DeleteFunc(f.provisioned, func(pc *lambda.ProvisionedConcurrencyConfigListItem) bool {
+		return aws.StringValue(pc.FunctionArn) == arn
+	})
+	return &lambda.DeleteProvisionedConcurrencyConfigOutput{}, nil
+}
+
+func TestPlugin_validateConcurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "zero reserved", config: Config{ReservedConcurrency: aws.Int64(0)}},
+		{name: "provisioned within reserved", config: Config{ReservedConcurrency: aws.Int64(10), ProvisionedConcurrency: 10}},
+		{name: "negative reserved", config: Config{ReservedConcurrency: aws.Int64(-1)}, wantErr: true},
+		{name: "negative provisioned", config: Config{ProvisionedConcurrency: -1}, wantErr: true},
+		{
+			name:    "provisioned exceeds reserved",
+			config:  Config{ReservedConcurrency: aws.Int64(5), ProvisionedConcurrency: 10},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Plugin{Config: tt.config}.validateConcurrency()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConcurrency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlugin_deploy_concurrency(t *testing.T) {
+	provisionedPollInterval = time.Millisecond
+	t.Cleanup(func() { provisionedPollInterval = 5 * time.Second })
+
+	tests := []struct {
+		name      string
+		config    Config
+		status    []string
+		existing  []string
+		aliases   []*lambda.AliasConfiguration
+		throttled string
+		wantCalls []string
+		wantTag   string
+		wantErr   string
+		wantKind  ErrorKind
+	}{
+		{
+			name:      "reserved",
+			config:    Config{ReservedConcurrency: aws.Int64(0)},
+			wantCalls: []string{"UpdateFunctionCode", "GetFunction:", "PutFunctionConcurrency"},
+		},
+		{
+			name:      "clear reserved",
+			config:    Config{Clear: []string{ClearReservedConcurrency}},
+			wantCalls: []string{"UpdateFunctionCode", "GetFunction:", "DeleteFunctionConcurrency"},
+		},
+		{
+			name:      "reserved of a throttled function is saved",
+			config:    Config{ReservedConcurrency: aws.Int64(50)},
+			throttled: "20",
+			wantCalls: []string{"UpdateFunctionCode", "GetFunction:"},
+			wantTag:   "50",
+		},
+		{
+			name:      "clear reserved of a throttled function is saved",
+			config:    Config{Clear: []string{ClearReservedConcurrency}},
+			throttled: "20",
+			wantCalls: []string{"UpdateFunctionCode", "GetFunction:"},
+			wantTag:   unreserved,
+		},
+		{
+			name:   "provisioned moves to the published version",
+			config: Config{ProvisionedConcurrency: 5},
+			status: []string{
+				lambda.ProvisionedConcurrencyStatusEnumInProgress,
+				lambda.ProvisionedConcurrencyStatusEnumReady,
+			},
+			existing: []string{"7", "live"},
+			wantCalls: []string{
+				"UpdateFunctionCode",
+				"PutProvisionedConcurrencyConfig:1",
+				"DeleteProvisionedConcurrencyConfig:7",
+			},
+		},
+		{
+			name:     "aliased versions keep provisioned concurrency",
+			config:   Config{ProvisionedConcurrency: 5},
+			status:   []string{lambda.ProvisionedConcurrencyStatusEnumReady},
+			existing: []string{"5", "6", "7"},
+			aliases: []*lambda.AliasConfiguration{{
+				Name:            aws.String("live"),
+				FunctionVersion: aws.String("6"),
+				RoutingConfig: &lambda.AliasRoutingConfiguration{
+					AdditionalVersionWeights: aws.Float64Map(map[string]float64{"7": 0.1}),
+				},
+			}},
+			wantCalls: []string{
+				"UpdateFunctionCode",
+				"PutProvisionedConcurrencyConfig:1",
+				"DeleteProvisionedConcurrencyConfig:5",
+			},
+		},
+		{
+			name:   "provisioned failed",
+			config: Config{ProvisionedConcurrency: 5},
+			status: []string{
+				lambda.ProvisionedConcurrencyStatusEnumInProgress,
+				lambda.ProvisionedConcurrencyStatusEnumFailed,
+			},
+			existing:  []string{"7"},
+			wantCalls: []string{"UpdateFunctionCode", "PutProvisionedConcurrencyConfig:1"},
+			wantErr:   "account concurrency limit reached",
+			wantKind:  KindUnknown,
+		},
+		{
+			name:      "provisioned timed out",
+			config:    Config{ProvisionedConcurrency: 5, MaxAttempts: 3},
+			status:    []string{lambda.ProvisionedConcurrencyStatusEnumInProgress},
+			wantCalls: []string{"UpdateFunctionCode", "PutProvisionedConcurrencyConfig:1"},
+			wantErr:   "is IN_PROGRESS",
+			wantKind:  KindWaiterTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeLambda()
+			svc.provisionedStatus = tt.status
+			svc.provisionedReason = "account concurrency limit reached"
+			svc.aliases = tt.aliases
+			if tt.throttled != "" {
+				svc.reserved = aws.Int64(0)
+				svc.tags[throttleTag] = tt.throttled
+			}
+			for _, qualifier := range tt.existing {
+				svc.provisioned = append(svc.provisioned, &lambda.ProvisionedConcurrencyConfigListItem{
+					FunctionArn:                              aws.String(svc.qualifiedArn(qualifier)),
+					RequestedProvisionedConcurrentExecutions: aws.Int64(5),
+				})
+			}
+
+			tt.config.FunctionName = "test"
+			tt.config.SkipTags = true
+			if tt.config.MaxAttempts == 0 {
+				tt.config.MaxAttempts = 10
+			}
+			p := &Plugin{Config: tt.config}
+			if err := p.validateClear(); err != nil {
+				t.Fatal(err)
+			}
+
+			err := p.deploy(context.Background(), svc, nil, &lambda.UpdateFunctionCodeInput{
+				FunctionName: aws.String("test"),
+				Publish:      aws.Bool(true),
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("deploy() error = %v, want %q", err, tt.wantErr)
+				}
+				if exitCode(err) != int(tt.wantKind) {
+					t.Errorf("exit code = %d, want %d", exitCode(err), tt.wantKind)
+				}
+			}
+			if !reflect.DeepEqual(svc.calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", svc.calls, tt.wantCalls)
+			}
+			if tt.throttled != "" {
+				if svc.reserved == nil || *svc.reserved != 0 {
+					t.Errorf("reserved = %v, want the function to stay throttled", svc.reserved)
+				}
+				if got := svc.tags[throttleTag]; got != tt.wantTag {
+					t.Errorf("throttle tag = %q, want %q", got, tt.wantTag)
+				}
+			}
+		})
+	}
+}
+
+func Test_qualifiedVersion(t *testing.T) {
+	tests := []struct {
+		arn    string
+		want   string
+		wantOK bool
+	}{
+		{arn: "arn:aws:lambda:us-east-1:123456789012:function:test:12", want: "12", wantOK: true},
+		{arn: "arn:aws:lambda:us-east-1:123456789012:function:test:live"},
+		{arn: "arn:aws:lambda:us-east-1:123456789012:function:test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arn, func(t *testing.T) {
+			got, ok := qualifiedVersion(tt.arn)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("qualifiedVersion() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// Configuration fields that can be cleared. Unset settings leave the
+// function unchanged, so clearing has to be asked for explicitly.
+const (
+	ClearDescription = "description"
+	ClearLayers      = "layers"
+	ClearVPC         = "vpc"
+	ClearTracing     = "tracing"
+	ClearEnvironment = "environment"
+
+	ClearReservedConcurrency = "reserved-concurrency"
+)
+
+var clearFields = []string{
+	ClearDescription, ClearLayers, ClearVPC, ClearTracing, ClearEnvironment,
+	ClearReservedConcurrency,
+}
+
+// clears reports whether the field is cleared.
+func (p Plugin) clears(field string) bool {
+	return slices.Contains(trimValues(p.Config.Clear), field)
+}
+
+// validateClear rejects unknown fields and fields that are set and
+// cleared at the same time.
+func (p Plugin) validateClear() error {
+	for _, field := range trimValues(p.Config.Clear) {
+		if !slices.Contains(clearFields, field) {
+			return configError("cannot clear %q, must be one of %v", field, clearFields)
+		}
+	}
+
+	conflicts := map[string]bool{
+		ClearDescription: p.Config.Description != "",
+		ClearLayers:      len(trimValues(p.Config.Layers)) > 0,
+		ClearVPC:         len(trimValues(p.Config.Subnets)) > 0 || len(trimValues(p.Config.SecurityGroups)) > 0,
+		ClearTracing:     p.Config.TracingMode != "",
+		ClearEnvironment: len(trimValues(p.Config.Environment)) > 0 ||
+			len(trimValues(p.Config.EnvironmentFile)) > 0 ||
+			len(trimValues(p.Config.EnvironmentRemove)) > 0,
+		ClearReservedConcurrency: p.Config.ReservedConcurrency != nil,
+	}
+	for _, field := range clearFields {
+		if p.clears(field) && conflicts[field] {
+			return configError("%s is both set and cleared", field)
+		}
+	}
+
+	return nil
+}
+
+// Limits of the ephemeral storage in MB.
+const (
+	minEphemeralStorage = 512
+	maxEphemeralStorage = 10240
+)
+
+// validateConfiguration checks the settings that the API would only
+// reject after the code was packaged.
+func (p Plugin) validateConfiguration() error {
+	if size := p.Config.EphemeralStorage; size != 0 && (size < minEphemeralStorage || size > maxEphemeralStorage) {
+		return configError("ephemeral storage must be between %d and %d MB", minEphemeralStorage, maxEphemeralStorage)
+	}
+	if v := p.Config.SnapStart; v != "" && !slices.Contains(lambda.SnapStartApplyOn_Values(), v) {
+		return configError("invalid snap start %q, must be one of %v", v, lambda.SnapStartApplyOn_Values())
+	}
+
+	if err := p.validateRuntimeManagement(); err != nil {
+		return err
+	}
+	if err := p.validateLogging(); err != nil {
+		return err
+	}
+	return p.validateConcurrency()
+}
+
+// configurationInput returns the configuration update for the settings,
+// or nil if no setting changes the configuration. Secret references in
+// the environment variables are not resolved yet.
+func (p Plugin) configurationInput(variables map[string]string) *lambda.UpdateFunctionConfigurationInput {
+	isUpdateConfig := false
+	cfg := &lambda.UpdateFunctionConfigurationInput{}
+	cfg.SetFunctionName(p.Config.FunctionName)
+	if p.Config.MemorySize > 0 {
+		isUpdateConfig = true
+		cfg.SetMemorySize(p.Config.MemorySize)
+	}
+	if p.Config.Timeout > 0 {
+		isUpdateConfig = true
+		cfg.SetTimeout(p.Config.Timeout)
+	}
+	if len(p.Config.Handler) > 0 {
+		isUpdateConfig = true
+		cfg.SetHandler(p.Config.Handler)
+	}
+	if len(p.Config.Role) > 0 {
+		isUpdateConfig = true
+		cfg.SetRole(p.Config.Role)
+	}
+	if len(p.Config.Runtime) > 0 {
+		isUpdateConfig = true
+		cfg.SetRuntime(p.Config.Runtime)
+	}
+	if p.Config.Description != "" || p.clears(ClearDescription) {
+		isUpdateConfig = true
+		cfg.SetDescription(p.Config.Description)
+	}
+	if p.Config.EphemeralStorage > 0 {
+		isUpdateConfig = true
+		cfg.SetEphemeralStorage(&lambda.EphemeralStorage{
+			Size: aws.Int64(p.Config.EphemeralStorage),
+		})
+	}
+	if p.Config.SnapStart != "" {
+		isUpdateConfig = true
+		cfg.SetSnapStart(&lambda.SnapStart{
+			ApplyOn: aws.String(p.Config.SnapStart),
+		})
+	}
+	if c := p.loggingConfig(); c != nil {
+		isUpdateConfig = true
+		cfg.SetLoggingConfig(c)
+	}
+	if len(p.Config.Layers) > 0 || p.clears(ClearLayers) {
+		isUpdateConfig = true
+		// an empty list removes every layer
+		cfg.SetLayers(aws.StringSlice(p.Config.Layers))
+	}
+
+	if len(variables) > 0 || len(trimValues(p.Config.EnvironmentRemove)) > 0 || p.clears(ClearEnvironment) {
+		isUpdateConfig = true
+		cfg.SetEnvironment(&lambda.Environment{Variables: aws.StringMap(variables)})
+	}
+
+	subnets := trimValues(p.Config.Subnets)
+	securityGroups := trimValues(p.Config.SecurityGroups)
+	if len(subnets) > 0 || len(securityGroups) > 0 || p.clears(ClearVPC) {
+		isUpdateConfig = true
+		// empty subnets and security groups detach the function from its VPC
+		cfg.SetVpcConfig(&lambda.VpcConfig{
+			Ipv6AllowedForDualStack: aws.Bool(p.Config.IP6DualStack),
+			SubnetIds:               aws.StringSlice(subnets),
+			SecurityGroupIds:        aws.StringSlice(securityGroups),
+		})
+	}
+
+	if p.Config.TracingMode != "" || p.clears(ClearTracing) {
+		isUpdateConfig = true
+		mode := p.Config.TracingMode
+		if mode == "" {
+			mode = lambda.TracingModePassThrough
+		}
+		cfg.SetTracingConfig(&lambda.TracingConfig{
+			Mode: aws.String(mode),
+		})
+	}
+
+	if !isUpdateConfig {
+		return nil
+	}
+
+	return cfg
+}
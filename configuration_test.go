@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func TestPlugin_configurationInput_clear(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{
+			name: "nothing to update",
+			want: "",
+		},
+		{
+			name:   "description",
+			config: Config{Clear: []string{ClearDescription}},
+			want:   `{"Description":""}`,
+		},
+		{
+			name:   "layers",
+			config: Config{Clear: []string{ClearLayers}},
+			want:   `{"Layers":[]}`,
+		},
+		{
+			name:   "vpc",
+			config: Config{Clear: []string{ClearVPC}},
+			want:   `{"VpcConfig":{"Ipv6AllowedForDualStack":false,"SecurityGroupIds":[],"SubnetIds":[]}}`,
+		},
+		{
+			name:   "tracing",
+			config: Config{Clear: []string{ClearTracing}},
+			want:   `{"TracingConfig":{"Mode":"PassThrough"}}`,
+		},
+		{
+			name:   "environment",
+			config: Config{Clear: []string{ClearEnvironment}},
+			want:   `{"Environment":{"Variables":{}}}`,
+		},
+		{
+			name:   "ephemeral storage and snap start",
+			config: Config{EphemeralStorage: 2048, SnapStart: lambda.SnapStartApplyOnPublishedVersions},
+			want:   `{"EphemeralStorage":{"Size":2048},"SnapStart":{"ApplyOn":"PublishedVersions"}}`,
+		},
+		{
+			name: "logging config",
+			config: Config{
+				FunctionLogFormat:   lambda.LogFormatJson,
+				ApplicationLogLevel: lambda.ApplicationLogLevelInfo,
+				LogGroup:            "/app/api",
+			},
+			want: `{"LoggingConfig":{"ApplicationLogLevel":"INFO","LogFormat":"JSON","LogGroup":"/app/api"}}`,
+		},
+		{
+			name:   "set fields are kept",
+			config: Config{Description: "api", Layers: []string{"arn:layer:1"}, TracingMode: "Active"},
+			want:   `{"Description":"api","Layers":["arn:layer:1"],"TracingConfig":{"Mode":"Active"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.FunctionName = "test"
+			p := Plugin{Config: tt.config}
+			if err := p.validateClear(); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := p.configurationInput(nil)
+			if tt.want == "" {
+				if cfg != nil {
+					t.Errorf("configurationInput() = %v, want nil", cfg)
+				}
+				return
+			}
+
+			// the function name is sent in the path, not in the body
+			body, err := jsonutil.BuildJSON(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("request body = %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlugin_validateClear(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "all fields", config: Config{Clear: []string{" layers", "vpc ", "description", "tracing", "environment"}}},
+		{name: "unknown field", config: Config{Clear: []string{"memory"}}, wantErr: true},
+		{name: "description set", config: Config{Clear: []string{"description"}, Description: "api"}, wantErr: true},
+		{name: "layers set", config: Config{Clear: []string{"layers"}, Layers: []string{"arn"}}, wantErr: true},
+		{name: "subnets set", config: Config{Clear: []string{"vpc"}, Subnets: []string{"subnet-1"}}, wantErr: true},
+		{name: "tracing set", config: Config{Clear: []string{"tracing"}, TracingMode: "Active"}, wantErr: true},
+		{
+			name:    "environment set",
+			config:  Config{Clear: []string{"environment"}, Environment: []string{"A=1"}},
+			wantErr: true,
+		},
+		{
+			name:    "environment file set",
+			config:  Config{Clear: []string{"environment"}, EnvironmentFile: []string{".env"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Plugin{Config: tt.config}.validateClear()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateClear() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && exitCode(err) != int(KindInvalidConfig) {
+				t.Errorf("exit code = %d, want %d", exitCode(err), KindInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestPlugin_deploy_clearEnvironmentMerge(t *testing.T) {
+	svc := newFakeLambda()
+	svc.config.Environment = &lambda.EnvironmentResponse{
+		Variables: aws.StringMap(map[string]string{"A": "1", "B": "2"}),
+	}
+
+	p := &Plugin{Config: Config{
+		FunctionName:    "test",
+		EnvironmentMode: EnvModeMerge,
+		Clear:           []string{ClearEnvironment},
+		SkipTags:        true,
+	}}
+	err := p.deploy(
+		context.Background(),
+		svc,
+		p.configurationInput(nil),
+		&lambda.UpdateFunctionCodeInput{FunctionName: aws.String("test")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := aws.StringValueMap(svc.config.Environment.Variables); !reflect.DeepEqual(got, map[string]string{}) {
+		t.Errorf("environment = %v, want no variables", got)
+	}
+}
+
+func TestPlugin_validateConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "ephemeral storage", config: Config{EphemeralStorage: 2048}},
+		{name: "ephemeral storage too small", config: Config{EphemeralStorage: 256}, wantErr: true},
+		{name: "ephemeral storage too large", config: Config{EphemeralStorage: 10241}, wantErr: true},
+		{name: "snap start", config: Config{SnapStart: lambda.SnapStartApplyOnPublishedVersions}},
+		{name: "snap start off", config: Config{SnapStart: lambda.SnapStartApplyOnNone}},
+		{name: "unknown snap start", config: Config{SnapStart: "Always"}, wantErr: true},
+		{name: "runtime management", config: Config{RuntimeUpdateMode: "Never"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Plugin{Config: tt.config}.validateConfiguration()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
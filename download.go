@@ -0,0 +1,238 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/urfave/cli/v2"
+)
+
+// downloadOptions selects the code package to download and what to do
+// with it.
+type downloadOptions struct {
+	Qualifier string
+	Output    string
+	Extract   string
+	List      bool
+}
+
+func downloadCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "download",
+		Usage: "Download the code package of a function, alias or version",
+		Flags: append([]cli.Flag{
+			functionNameFlag(),
+			&cli.StringFlag{
+				Name:    "qualifier",
+				Usage:   "The version or alias to download",
+				EnvVars: []string{"PLUGIN_QUALIFIER", "QUALIFIER", "INPUT_QUALIFIER"},
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Path of the downloaded zip, defaults to <function>-<version>.zip",
+				EnvVars: []string{"PLUGIN_OUTPUT", "OUTPUT", "INPUT_OUTPUT"},
+			},
+			&cli.StringFlag{
+				Name:    "extract",
+				Usage:   "Extract the package into this directory",
+				EnvVars: []string{"PLUGIN_EXTRACT", "EXTRACT", "INPUT_EXTRACT"},
+			},
+			&cli.BoolFlag{
+				Name:    "list",
+				Usage:   "Print the files in the package",
+				EnvVars: []string{"PLUGIN_LIST", "LIST", "INPUT_LIST"},
+			},
+		}, awsFlags()...),
+		Action: func(c *cli.Context) error {
+			p, err := commandPlugin(c)
+			if err != nil {
+				return err
+			}
+
+			sess, err := p.newSession()
+			if err != nil {
+				return wrapError("create session", err)
+			}
+
+			return p.download(c.Context, lambda.New(sess), http.DefaultClient, downloadOptions{
+				Qualifier: c.String("qualifier"),
+				Output:    c.String("output"),
+				Extract:   c.String("extract"),
+				List:      c.Bool("list"),
+			}, os.Stdout)
+		},
+	}
+}
+
+// download fetches the code package from the presigned URL returned by
+// GetFunction and verifies it against the CodeSha256 of the function.
+func (p *Plugin) download(
+	ctx context.Context,
+	svc lambdaiface.LambdaAPI,
+	client *http.Client,
+	opts downloadOptions,
+	w io.Writer,
+) error {
+	if p.Config.FunctionName == "" {
+		return configError("missing lambda function name")
+	}
+
+	input := &lambda.GetFunctionInput{FunctionName: aws.String(p.Config.FunctionName)}
+	if opts.Qualifier != "" {
+		input.SetQualifier(opts.Qualifier)
+	}
+	fn, err := svc.GetFunctionWithContext(ctx, input)
+	if err != nil {
+		return wrapError("get function", err)
+	}
+	if fn.Code == nil || aws.StringValue(fn.Code.Location) == "" {
+		return configError("function %s has no zip package, it is deployed from an image", p.Config.FunctionName)
+	}
+
+	c := fn.Configuration
+	path := opts.Output
+	if path == "" {
+		version := strings.Trim(aws.StringValue(c.Version), "$")
+		path = aws.StringValue(c.FunctionName) + "-" + strings.ToLower(version) + ".zip"
+	}
+
+	logger := p.logger().With("phase", "download")
+	logger.Info("download code package",
+		"function_version", aws.StringValue(c.Version),
+		"code_sha256", aws.StringValue(c.CodeSha256),
+		"path", path,
+	)
+	if err := fetch(ctx, client, aws.StringValue(fn.Code.Location), path); err != nil {
+		return err
+	}
+
+	m, err := newManifest(path)
+	if err != nil {
+		return err
+	}
+	if m.CodeSha256 != aws.StringValue(c.CodeSha256) {
+		_ = os.Remove(path)
+		return &Error{
+			Kind: KindUnknown,
+			Op:   "verify code package",
+			Err: fmt.Errorf("code sha256 is %s, the function reports %s",
+				m.CodeSha256, aws.StringValue(c.CodeSha256)),
+			Hint: "the download is incomplete or corrupted, retry",
+		}
+	}
+	logger.Info("code package verified", "size", m.Size, "files", len(m.Files))
+
+	if opts.List {
+		for _, f := range m.Files {
+			if _, err := fmt.Fprintf(w, "%10d  %s\n", f.Size, f.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.Extract != "" {
+		if err := extractZip(path, opts.Extract); err != nil {
+			return fmt.Errorf("extract code package: %w", err)
+		}
+		logger.Info("code package extracted", "dir", opts.Extract)
+	}
+
+	return nil
+}
+
+// fetch downloads url to path.
+func fetch(ctx context.Context, client *http.Client, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("download code package: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &Error{
+			Kind: KindUnknown,
+			Op:   "download code package",
+			Err:  fmt.Errorf("unexpected status %s", resp.Status),
+			Hint: "the presigned url is only valid for 10 minutes, retry",
+		}
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return fmt.Errorf("download code package: %w", err)
+	}
+
+	return out.Close()
+}
+
+// extractZip extracts the zip at path into dir. Entries that would be
+// written outside of dir are rejected.
+func extractZip(path, dir string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		dest := filepath.Join(dir, filepath.FromSlash(f.Name))
+		rel, err := filepath.Rel(dir, dest)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return errors.New("illegal file path in zip: " + f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extractFile(f, dest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractFile(f *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, f.Mode().Perm()|0o200)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil { //nolint:gosec // the package is verified
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
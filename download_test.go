@@ -0,0 +1,182 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// newCodeStub serves the zip built from files at a presigned URL stand-in
+// and returns the URL and the base64 sha256 of the zip.
+func newCodeStub(t *testing.T, files map[string]string) (string, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeTree(t, dir, files)
+	m, err := packageSources([]string{filepath.Join(dir, "*")}, filepath.Join(t.TempDir(), "code.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(m.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("X-Amz-Signature") != "signed" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv.URL + "/code.zip?X-Amz-Signature=signed", m.CodeSha256
+}
+
+func TestPlugin_download(t *testing.T) {
+	location, sha := newCodeStub(t, map[string]string{
+		"bootstrap":      "binary",
+		"config/app.yml": "port: 8080",
+	})
+
+	tests := []struct {
+		name       string
+		location   string
+		codeSha256 string
+		opts       downloadOptions
+		wantList   string
+		wantKind   ErrorKind
+	}{
+		{
+			name:       "list and extract",
+			location:   location,
+			codeSha256: sha,
+			opts:       downloadOptions{Qualifier: "live", List: true, Extract: "src"},
+			wantList:   "         6  bootstrap\n        10  config/app.yml\n",
+		},
+		{
+			name:       "checksum mismatch",
+			location:   location,
+			codeSha256: "c2hhMjU2",
+			wantKind:   KindUnknown,
+		},
+		{
+			name:       "expired url",
+			location:   location[:len(location)-len("signed")] + "expired",
+			codeSha256: sha,
+			wantKind:   KindUnknown,
+		},
+		{
+			name:     "image function",
+			wantKind: KindInvalidConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			svc := newFakeLambda()
+			svc.config.Version = aws.String("$LATEST")
+			svc.config.CodeSha256 = aws.String(tt.codeSha256)
+			svc.code = &lambda.FunctionCodeLocation{Location: aws.String(tt.location)}
+			if tt.location == "" {
+				svc.code = &lambda.FunctionCodeLocation{ImageUri: aws.String("123456789012.dkr.ecr.us-east-1.amazonaws.com/api:1")}
+			}
+			if tt.opts.Extract != "" {
+				tt.opts.Extract = filepath.Join(dir, tt.opts.Extract)
+			}
+
+			var out bytes.Buffer
+			p := &Plugin{Config: Config{FunctionName: "test"}}
+			t.Chdir(dir)
+			err := p.download(context.Background(), svc, http.DefaultClient, tt.opts, &out)
+			if tt.wantKind != 0 {
+				if exitCode(err) != int(tt.wantKind) {
+					t.Fatalf("download() error = %v, want kind %v", err, tt.wantKind)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "test-latest.zip")); !os.IsNotExist(err) {
+					t.Error("the unverified package was kept")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if svc.calls[0] != "GetFunction:"+tt.opts.Qualifier {
+				t.Errorf("calls = %v, want qualifier %q", svc.calls, tt.opts.Qualifier)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "test-latest.zip")); err != nil {
+				t.Error(err)
+			}
+			if out.String() != tt.wantList {
+				t.Errorf("list = %q, want %q", out.String(), tt.wantList)
+			}
+			got, err := os.ReadFile(filepath.Join(tt.opts.Extract, "config", "app.yml"))
+			if err != nil || string(got) != "port: 8080" {
+				t.Errorf("extracted config/app.yml = %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func Test_extractZip(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		entry   string
+		want    string
+		wantErr bool
+	}{
+		{name: "directory", dir: "out", entry: "app/main.py", want: "out/app/main.py"},
+		{name: "current directory", dir: ".", entry: "app/main.py", want: "app/main.py"},
+		{name: "illegal path", dir: "out", entry: "../evil.txt", wantErr: true},
+		{name: "illegal path from current directory", dir: ".", entry: "../evil.txt", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			work := filepath.Join(root, "work")
+			if err := os.Mkdir(work, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Chdir(work)
+
+			path := filepath.Join(root, "code.zip")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := zip.NewWriter(f)
+			if _, err := w.Create(tt.entry); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			err = extractZip(path, tt.dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractZip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if _, err := os.Stat(filepath.Join(root, "evil.txt")); !os.IsNotExist(err) {
+					t.Error("evil.txt was written")
+				}
+				return
+			}
+			if _, err := os.Stat(filepath.Join(work, tt.want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+// Environment modes.
+const (
+	// EnvModeReplace replaces every variable of the function.
+	EnvModeReplace = "replace"
+	// EnvModeMerge overlays the variables on the existing ones.
+	EnvModeMerge = "merge"
+)
+
+const maskedValue = "******"
+
+func (p Plugin) validateEnvironmentMode() error {
+	switch p.Config.EnvironmentMode {
+	case "", EnvModeReplace:
+		if len(trimValues(p.Config.EnvironmentRemove)) > 0 {
+			return configError("environment-remove requires environment-mode %q", EnvModeMerge)
+		}
+	case EnvModeMerge:
+	default:
+		return configError("invalid environment mode %q", p.Config.EnvironmentMode)
+	}
+
+	return nil
+}
+
+// mergeEnvironment overlays updates on current and drops the removed keys.
+func mergeEnvironment(current, updates map[string]string, remove []string) map[string]string {
+	output := make(map[string]string, len(current)+len(updates))
+	maps.Copy(output, current)
+	maps.Copy(output, updates)
+	for _, key := range remove {
+		delete(output, key)
+	}
+	return output
+}
+
+// diffEnvironment returns the sorted keys added, changed and removed
+// between the old and the new set of variables.
+func diffEnvironment(old, new map[string]string) (added, changed, removed []string) {
+	for key, value := range new {
+		prev, ok := old[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case prev != value:
+			changed = append(changed, key)
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(changed)
+	slices.Sort(removed)
+	return added, changed, removed
+}
+
+func logEnvironmentDiff(logger *slog.Logger, old, new map[string]string) {
+	added, changed, removed := diffEnvironment(old, new)
+	for _, key := range added {
+		logger.Info("environment added", "key", key, "value", maskedValue)
+	}
+	for _, key := range changed {
+		logger.Info("environment changed", "key", key, "value", maskedValue)
+	}
+	for _, key := range removed {
+		logger.Info("environment removed", "key", key)
+	}
+}
+
+// applyEnvironment resolves the final set of variables for env according
+// to the environment mode, given the current function configuration.
+func (p *Plugin) applyEnvironment(current *lambda.FunctionConfiguration, env *lambda.Environment) {
+	var existing map[string]string
+	if current != nil && current.Environment != nil {
+		existing = aws.StringValueMap(current.Environment.Variables)
+	}
+
+	variables := aws.StringValueMap(env.Variables)
+	if p.Config.EnvironmentMode == EnvModeMerge && !p.clears(ClearEnvironment) {
+		variables = mergeEnvironment(existing, variables, trimValues(p.Config.EnvironmentRemove))
+		env.Variables = aws.StringMap(variables)
+	}
+
+	logEnvironmentDiff(p.logger().With("phase", "config"), existing, variables)
+}
+
+var envKeyPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// readEnvironmentFile parses a dotenv, JSON or YAML file, chosen by its
+// extension. Unlike the inline environment, malformed entries are errors.
+func readEnvironmentFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		d := json.NewDecoder(bytes.NewReader(content))
+		d.UseNumber()
+		err = d.Decode(&values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	default:
+		var env map[string]string
+		env, err = unmarshalDotenv(content)
+		values = make(map[string]any, len(env))
+		for key, value := range env {
+			values[key] = value
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	output := make(map[string]string, len(values))
+	for key, value := range values {
+		if !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid environment variable name %q", key)
+		}
+
+		switch v := value.(type) {
+		case nil:
+			output[key] = ""
+		case string:
+			output[key] = v
+		case json.Number, bool, int, int64, uint64, float64:
+			output[key] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("environment variable %s must be a scalar value", key)
+		}
+	}
+
+	return output, nil
+}
+
+// dollarPlaceholder stands in for "$" while godotenv parses a file.
+const dollarPlaceholder = "\uE000"
+
+// unmarshalDotenv parses a dotenv file without expanding variables.
+// godotenv replaces $X and ${X} itself and substitutes "" for unknown
+// names, so ${NAME} references would never reach expandEnvironment.
+func unmarshalDotenv(content []byte) (map[string]string, error) {
+	if bytes.Contains(content, []byte(dollarPlaceholder)) {
+		return nil, fmt.Errorf("unsupported character U+E000")
+	}
+
+	env, err := godotenv.UnmarshalBytes(bytes.ReplaceAll(content, []byte("$"), []byte(dollarPlaceholder)))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range env {
+		env[key] = strings.ReplaceAll(value, dollarPlaceholder, "$")
+	}
+	return env, nil
+}
+
+// environmentVariables layers the environment files in order and then
+// the inline environment on top of them.
+func (p Plugin) environmentVariables() (map[string]string, error) {
+	output := make(map[string]string)
+	for _, path := range trimValues(p.Config.EnvironmentFile) {
+		values, err := readEnvironmentFile(path)
+		if err != nil {
+			return nil, configError("environment file %s: %w", path, err)
+		}
+		maps.Copy(output, values)
+	}
+
+	maps.Copy(output, getEnvironment(trimValues(p.Config.Environment)))
+	return output, nil
+}
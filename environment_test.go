@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func Test_mergeEnvironment(t *testing.T) {
+	current := map[string]string{"A": "1", "B": "2", "C": "3"}
+	updates := map[string]string{"B": "20", "D": "4"}
+
+	got := mergeEnvironment(current, updates, []string{"C", "MISSING"})
+	want := map[string]string{"A": "1", "B": "20", "D": "4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeEnvironment() = %v, want %v", got, want)
+	}
+	if current["B"] != "2" {
+		t.Errorf("mergeEnvironment() modified the current variables")
+	}
+}
+
+func Test_diffEnvironment(t *testing.T) {
+	added, changed, removed := diffEnvironment(
+		map[string]string{"A": "1", "B": "2", "C": "3"},
+		map[string]string{"A": "1", "B": "20", "D": "4", "E": "5"},
+	)
+	if want := []string{"D", "E"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added = %v, want %v", added, want)
+	}
+	if want := []string{"B"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"C"}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+}
+
+func TestPlugin_validateEnvironmentMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "default", config: Config{}},
+		{name: "replace", config: Config{EnvironmentMode: EnvModeReplace}},
+		{name: "merge", config: Config{EnvironmentMode: EnvModeMerge, EnvironmentRemove: []string{"A"}}},
+		{name: "invalid", config: Config{EnvironmentMode: "append"}, wantErr: true},
+		{
+			name:    "remove without merge",
+			config:  Config{EnvironmentMode: EnvModeReplace, EnvironmentRemove: []string{"A"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Plugin{Config: tt.config}.validateEnvironmentMode()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateEnvironmentMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlugin_deploy_environment(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   map[string]string
+	}{
+		{
+			name:   "replace",
+			config: Config{EnvironmentMode: EnvModeReplace},
+			want:   map[string]string{"VERSION": "2"},
+		},
+		{
+			name:   "merge",
+			config: Config{EnvironmentMode: EnvModeMerge},
+			want:   map[string]string{"DB_HOST": "db", "VERSION": "2", "LEGACY": "true"},
+		},
+		{
+			name:   "merge with remove list",
+			config: Config{EnvironmentMode: EnvModeMerge, EnvironmentRemove: []string{"LEGACY"}},
+			want:   map[string]string{"DB_HOST": "db", "VERSION": "2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeLambda()
+			svc.config.Environment = &lambda.EnvironmentResponse{
+				Variables: aws.StringMap(map[string]string{
+					"DB_HOST": "db",
+					"VERSION": "1",
+					"LEGACY":  "true",
+				}),
+			}
+
+			tt.config.FunctionName = "test"
+			p := &Plugin{Config: tt.config}
+			cfg := &lambda.UpdateFunctionConfigurationInput{FunctionName: aws.String("test")}
+			cfg.SetEnvironment(&lambda.Environment{
+				Variables: aws.StringMap(map[string]string{"VERSION": "2"}),
+			})
+			if err := p.deploy(
+				context.Background(),
+				svc,
+				cfg,
+				&lambda.UpdateFunctionCodeInput{FunctionName: aws.String("test")},
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			got := aws.StringValueMap(svc.configInput.Environment.Variables)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("environment = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlugin_environmentVariables(t *testing.T) {
+	dotenv := writeFile(t, "app.env", "# shared settings\nLOG_LEVEL=info\nexport REGION=eu\nNAME=\"dotenv\"\n")
+	jsonFile := writeFile(t, "app.json", `{"NAME": "json", "PORT": 8080, "DEBUG": false, "RATIO": 0.5}`)
+	yamlFile := writeFile(t, "app.yaml", "NAME: yaml\nREGION: us\nEMPTY:\n")
+
+	p := Plugin{Config: Config{
+		EnvironmentFile: []string{dotenv, jsonFile, yamlFile},
+		Environment:     []string{"NAME=inline"},
+	}}
+	got, err := p.environmentVariables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"LOG_LEVEL": "info",
+		"REGION":    "us",
+		"NAME":      "inline",
+		"PORT":      "8080",
+		"DEBUG":     "false",
+		"RATIO":     "0.5",
+		"EMPTY":     "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("environmentVariables() = %v, want %v", got, want)
+	}
+}
+
+func Test_readEnvironmentFile_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "dotenv missing value", file: "app.env", content: "A=1\nd\n"},
+		{name: "dotenv empty key", file: "app.env", content: "=1\n"},
+		{name: "dotenv invalid key", file: "app.env", content: "MY KEY=1\n"},
+		{name: "dotenv unterminated quote", file: "app.env", content: "A='1\n"},
+		{name: "json syntax", file: "app.json", content: `{"A": 1`},
+		{name: "json nested", file: "app.json", content: `{"A": {"B": 1}}`},
+		{name: "json list", file: "app.json", content: `["A=1"]`},
+		{name: "yaml nested", file: "app.yml", content: "A:\n  - 1\n"},
+		{name: "yaml invalid key", file: "app.yml", content: "1A: b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readEnvironmentFile(writeFile(t, tt.file, tt.content)); err == nil {
+				t.Errorf("readEnvironmentFile() expected error")
+			}
+		})
+	}
+
+	if _, err := readEnvironmentFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Errorf("readEnvironmentFile() expected error for a missing file")
+	}
+}
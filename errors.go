@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// ErrorKind classifies deploy failures. Each kind maps to a distinct
+// process exit code so pipelines can react to the type of failure.
+type ErrorKind int
+
+// Error kinds and their exit codes.
+const (
+	KindUnknown          ErrorKind = 1
+	KindInvalidConfig    ErrorKind = 2
+	KindNotFound         ErrorKind = 3
+	KindConflict         ErrorKind = 4
+	KindThrottled        ErrorKind = 5
+	KindStorageExceeded  ErrorKind = 6
+	KindWaiterTimeout    ErrorKind = 7
+	KindPermissionDenied ErrorKind = 8
+	KindFunctionError    ErrorKind = 9
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindInvalidConfig:
+		return "invalid configuration"
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindThrottled:
+		return "throttled"
+	case KindStorageExceeded:
+		return "code storage exceeded"
+	case KindWaiterTimeout:
+		return "waiter timeout"
+	case KindPermissionDenied:
+		return "permission denied"
+	case KindFunctionError:
+		return "function error"
+	default:
+		return "unknown"
+	}
+}
+
+// Error is returned by the plugin for every failure it can classify.
+type Error struct {
+	Kind ErrorKind
+	Op   string
+	Hint string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.String()
+	if e.Op != "" {
+		msg = e.Op + ": " + msg
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Hint != "" {
+		msg += " (hint: " + e.Hint + ")"
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the process exit code for the error.
+func (e *Error) ExitCode() int {
+	return int(e.Kind)
+}
+
+func configError(format string, args ...any) error {
+	return &Error{
+		Kind: KindInvalidConfig,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+// wrapError classifies an error returned by the AWS SDK during op and
+// returns an *Error with an actionable hint.
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	e = &Error{
+		Kind: KindUnknown,
+		Op:   op,
+		Err:  err,
+	}
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case lambda.ErrCodeResourceNotFoundException:
+			e.Kind = KindNotFound
+			e.Hint = "check the function name and region"
+		case lambda.ErrCodeResourceConflictException:
+			e.Kind = KindConflict
+			e.Hint = "another update is in progress, retry once it has finished"
+		case lambda.ErrCodePreconditionFailedException:
+			e.Kind = KindConflict
+			e.Hint = "the function was modified since the revision id was read"
+		case lambda.ErrCodeResourceNotReadyException:
+			e.Kind = KindConflict
+			e.Hint = "the function is inactive and is being reactivated, retry later"
+		case lambda.ErrCodeTooManyRequestsException:
+			e.Kind = KindThrottled
+			e.Hint = "the request rate limit was exceeded, retry later"
+		case lambda.ErrCodeCodeStorageExceededException:
+			e.Kind = KindStorageExceeded
+			e.Hint = "enable pruning or delete unused function versions"
+		case lambda.ErrCodeInvalidParameterValueException,
+			lambda.ErrCodeCodeVerificationFailedException,
+			lambda.ErrCodeInvalidCodeSignatureException,
+			lambda.ErrCodeInvalidRequestContentException,
+			lambda.ErrCodeRequestTooLargeException,
+			"ValidationException",
+			request.InvalidParameterErrCode,
+			request.ParamRequiredErrCode:
+			e.Kind = KindInvalidConfig
+		case "AccessDeniedException", "UnrecognizedClientException", "ExpiredTokenException":
+			e.Kind = KindPermissionDenied
+			e.Hint = "check the credentials and the IAM policy of the deploy role"
+		case request.WaiterResourceNotReadyErrorCode:
+			e.Kind = KindWaiterTimeout
+			e.Hint = "increase max-attempts or check the function state in the console"
+		case lambda.ErrCodeServiceException:
+			e.Hint = "the Lambda service had an internal error, retry later"
+		}
+	}
+
+	return e
+}
+
+// revisionConflict replaces the hint of a failed revision id precondition
+// with the revision the deploy expected to find.
+func revisionConflict(err error, revisionID string) error {
+	var e *Error
+	var aerr awserr.Error
+	if errors.As(err, &e) && errors.As(err, &aerr) &&
+		aerr.Code() == lambda.ErrCodePreconditionFailedException {
+		e.Hint = fmt.Sprintf(
+			"the function was modified by someone else during the deploy, expected revision %s",
+			revisionID,
+		)
+	}
+
+	return err
+}
+
+// exitCode returns the process exit code for err.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.ExitCode()
+	}
+
+	return int(KindUnknown)
+}
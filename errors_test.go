@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func Test_wrapError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{
+			name: "not found",
+			err:  awserr.New(lambda.ErrCodeResourceNotFoundException, "function not found", nil),
+			want: KindNotFound,
+		},
+		{
+			name: "conflict",
+			err:  awserr.New(lambda.ErrCodeResourceConflictException, "update in progress", nil),
+			want: KindConflict,
+		},
+		{
+			name: "precondition failed",
+			err:  awserr.New(lambda.ErrCodePreconditionFailedException, "revision mismatch", nil),
+			want: KindConflict,
+		},
+		{
+			name: "throttled",
+			err:  awserr.New(lambda.ErrCodeTooManyRequestsException, "rate exceeded", nil),
+			want: KindThrottled,
+		},
+		{
+			name: "storage exceeded",
+			err:  awserr.New(lambda.ErrCodeCodeStorageExceededException, "storage exceeded", nil),
+			want: KindStorageExceeded,
+		},
+		{
+			name: "invalid parameter",
+			err:  awserr.New(lambda.ErrCodeInvalidParameterValueException, "bad memory size", nil),
+			want: KindInvalidConfig,
+		},
+		{
+			name: "waiter timeout",
+			err:  awserr.New(request.WaiterResourceNotReadyErrorCode, "exceeded wait attempts", nil),
+			want: KindWaiterTimeout,
+		},
+		{
+			name: "access denied",
+			err:  awserr.New("AccessDeniedException", "not authorized", nil),
+			want: KindPermissionDenied,
+		},
+		{
+			name: "plain error",
+			err:  errors.New("connection reset"),
+			want: KindUnknown,
+		},
+		{
+			name: "already classified",
+			err:  fmt.Errorf("wrapped: %w", configError("missing function name")),
+			want: KindInvalidConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapError("update function code", tt.err)
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("wrapError() = %T, want *Error", err)
+			}
+			if e.Kind != tt.want {
+				t.Errorf("wrapError() kind = %v, want %v", e.Kind, tt.want)
+			}
+			if !errors.Is(err, tt.err) && !errors.Is(tt.err, err) {
+				t.Errorf("wrapError() does not wrap the original error")
+			}
+			if got := exitCode(err); got != int(tt.want) {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_Error(t *testing.T) {
+	err := wrapError(
+		"update function code",
+		awserr.New(lambda.ErrCodeCodeStorageExceededException, "storage exceeded", nil),
+	)
+	want := "update function code: code storage exceeded: " +
+		"CodeStorageExceededException: storage exceeded " +
+		"(hint: enable pruning or delete unused function versions)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	if got := exitCode(nil); got != 0 {
+		t.Errorf("exitCode(nil) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/joho/godotenv"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Export formats.
+const (
+	ExportFormatYAML = "yaml"
+	ExportFormatEnv  = "env"
+)
+
+// Redaction of environment values in an export.
+const (
+	RedactNone    = "none"
+	RedactSecrets = "secrets"
+	RedactAll     = "all"
+)
+
+// setting is a plugin setting with its value, a string, an int64, a bool
+// or a []string.
+type setting struct {
+	Name  string
+	Value any
+}
+
+// functionExport is the live configuration of a function, as plugin
+// settings plus notes about what the plugin does not manage.
+type functionExport struct {
+	Arn      string
+	Settings []setting
+	Notes    []string
+}
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Write the live function configuration as plugin settings",
+		Description: "The yaml format is the settings block of a Drone step, " +
+			"the env format can be loaded with PLUGIN_ENV_FILE.",
+		Flags: append([]cli.Flag{
+			functionNameFlag(),
+			&cli.StringFlag{
+				Name:    "format",
+				Usage:   "yaml or env",
+				Value:   ExportFormatYAML,
+				EnvVars: []string{"PLUGIN_EXPORT_FORMAT", "EXPORT_FORMAT", "INPUT_EXPORT_FORMAT"},
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Write the settings to this path instead of stdout",
+				EnvVars: []string{"PLUGIN_OUTPUT", "OUTPUT", "INPUT_OUTPUT"},
+			},
+			&cli.StringFlag{
+				Name:    "redact",
+				Usage:   "Mask environment values: none, secrets (keys matching secret-patterns) or all",
+				Value:   RedactNone,
+				EnvVars: []string{"PLUGIN_REDACT", "REDACT", "INPUT_REDACT"},
+			},
+			&cli.StringSliceFlag{
+				Name:    "secret-patterns",
+				Usage:   "Environment variable name patterns whose values are masked with redact secrets",
+				Value:   cli.NewStringSlice(defaultSecretPatterns...),
+				EnvVars: []string{"PLUGIN_SECRET_PATTERNS", "SECRET_PATTERNS", "INPUT_SECRET_PATTERNS"},
+			},
+		}, awsFlags()...),
+		Action: func(c *cli.Context) error {
+			p, err := commandPlugin(c)
+			if err != nil {
+				return err
+			}
+			p.Config.SecretPatterns = c.StringSlice("secret-patterns")
+
+			sess, err := p.newSession()
+			if err != nil {
+				return wrapError("create session", err)
+			}
+
+			e, err := p.exportFunction(c.Context, lambda.New(sess), c.String("redact"))
+			if err != nil {
+				return err
+			}
+			content, err := e.render(c.String("format"))
+			if err != nil {
+				return err
+			}
+
+			if path := c.String("output"); path != "" {
+				if err := os.WriteFile(path, content, 0o600); err != nil {
+					return fmt.Errorf("write export: %w", err)
+				}
+				p.logger().Info("function exported", "phase", "export", "path", path)
+				return nil
+			}
+			_, err = os.Stdout.Write(content)
+			return err
+		},
+	}
+}
+
+// escapeTemplate keeps ${NAME} in exported values from being expanded
+// when the settings are deployed again.
+func escapeTemplate(value string) string {
+	return strings.ReplaceAll(value, "${", "$${")
+}
+
+// escapeDrone doubles every $ of a setting value, since Drone substitutes
+// variables in .drone.yml before the plugin reads the settings.
+func escapeDrone(value any) any {
+	switch v := value.(type) {
+	case string:
+		return strings.ReplaceAll(v, "$", "$$")
+	case []string:
+		escaped := make([]string, 0, len(v))
+		for _, s := range v {
+			escaped = append(escaped, strings.ReplaceAll(s, "$", "$$"))
+		}
+		return escaped
+	}
+	return value
+}
+
+// exportFunction reads the live configuration of the function.
+func (p *Plugin) exportFunction(
+	ctx context.Context, svc lambdaiface.LambdaAPI, redact string,
+) (*functionExport, error) {
+	if p.Config.FunctionName == "" {
+		return nil, configError("missing lambda function name")
+	}
+	if !slices.Contains([]string{RedactNone, RedactSecrets, RedactAll}, redact) {
+		return nil, configError("invalid redact mode %q, must be none, secrets or all", redact)
+	}
+
+	fn, err := svc.GetFunctionWithContext(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+	})
+	if err != nil {
+		return nil, wrapError("get function", err)
+	}
+
+	c := fn.Configuration
+	e := &functionExport{Arn: aws.StringValue(c.FunctionArn)}
+	add := func(name string, value any) {
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return
+			}
+		case int64:
+			if v == 0 {
+				return
+			}
+		case bool:
+			if !v {
+				return
+			}
+		case []string:
+			if len(v) == 0 {
+				return
+			}
+		}
+		e.Settings = append(e.Settings, setting{Name: name, Value: value})
+	}
+
+	add("function_name", aws.StringValue(c.FunctionName))
+	add("runtime", aws.StringValue(c.Runtime))
+	add("handler", aws.StringValue(c.Handler))
+	add("role", aws.StringValue(c.Role))
+	add("memory_size", aws.Int64Value(c.MemorySize))
+	add("timeout", aws.Int64Value(c.Timeout))
+	add("description", escapeTemplate(aws.StringValue(c.Description)))
+	add("architectures", aws.StringValueSlice(c.Architectures))
+	var layers []string
+	for _, layer := range c.Layers {
+		layers = append(layers, aws.StringValue(layer.Arn))
+	}
+	add("layers", layers)
+	if c.VpcConfig != nil {
+		add("subnets", aws.StringValueSlice(c.VpcConfig.SubnetIds))
+		add("security_groups", aws.StringValueSlice(c.VpcConfig.SecurityGroupIds))
+		add("ipv6_dual_stack", aws.BoolValue(c.VpcConfig.Ipv6AllowedForDualStack))
+	}
+	if c.TracingConfig != nil && aws.StringValue(c.TracingConfig.Mode) != lambda.TracingModePassThrough {
+		add("tracing_mode", aws.StringValue(c.TracingConfig.Mode))
+	}
+	// the defaults are left out, like the plugin does when they are unset
+	if c.EphemeralStorage != nil && aws.Int64Value(c.EphemeralStorage.Size) != minEphemeralStorage {
+		add("ephemeral_storage", aws.Int64Value(c.EphemeralStorage.Size))
+	}
+	if c.SnapStart != nil && aws.StringValue(c.SnapStart.ApplyOn) != lambda.SnapStartApplyOnNone {
+		add("snap_start", aws.StringValue(c.SnapStart.ApplyOn))
+	}
+	if l := c.LoggingConfig; l != nil && aws.StringValue(l.LogFormat) == lambda.LogFormatJson {
+		add("function_log_format", aws.StringValue(l.LogFormat))
+		add("application_log_level", aws.StringValue(l.ApplicationLogLevel))
+		add("system_log_level", aws.StringValue(l.SystemLogLevel))
+	}
+	if l := c.LoggingConfig; l != nil && aws.StringValue(l.LogGroup) != "/aws/lambda/"+aws.StringValue(c.FunctionName) {
+		add("log_group", aws.StringValue(l.LogGroup))
+	}
+	if fn.Code != nil {
+		add("image_uri", aws.StringValue(fn.Code.ImageUri))
+	}
+
+	if c.Environment != nil {
+		variables := aws.StringValueMap(c.Environment.Variables)
+		env := make([]string, 0, len(variables))
+		redacted := false
+		for _, key := range slices.Sorted(maps.Keys(variables)) {
+			value := variables[key]
+			if redact == RedactAll || (redact == RedactSecrets && p.isSecretKey(key)) {
+				value = maskedValue
+				redacted = true
+			}
+			if strings.Contains(value, ",") {
+				p.logger().Warn("environment value contains a comma, move it to an environment file",
+					"phase", "export", "key", key)
+			}
+			env = append(env, key+"="+escapeTemplate(value))
+		}
+		add("environment", env)
+		if redacted {
+			e.Notes = append(e.Notes,
+				"environment values shown as "+maskedValue+" were redacted and must be replaced")
+		}
+	}
+
+	var tags []string
+	for key, value := range aws.StringValueMap(fn.Tags) {
+		// managed tags are set by every deploy, aws: tags by AWS itself
+		if strings.HasPrefix(key, managedTagPrefix) || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		tags = append(tags, key+"="+value)
+	}
+	slices.Sort(tags)
+	add("tags", tags)
+
+	if previous, ok := fn.Tags[throttleTag]; ok {
+		e.Notes = append(e.Notes, fmt.Sprintf("throttled, reserved concurrency before: %s",
+			aws.StringValue(previous)))
+	}
+	if fn.Concurrency != nil && fn.Concurrency.ReservedConcurrentExecutions != nil {
+		// zero is a valid reserved concurrency, so add cannot be used
+		e.Settings = append(e.Settings, setting{
+			Name:  "reserved_concurrency",
+			Value: aws.Int64Value(fn.Concurrency.ReservedConcurrentExecutions),
+		})
+	}
+	// provisioned concurrency on a single version is moved by every
+	// deploy; on aliases or several versions it is managed elsewhere
+	var versioned, others []*lambda.ProvisionedConcurrencyConfigListItem
+	if err := svc.ListProvisionedConcurrencyConfigsPagesWithContext(ctx,
+		&lambda.ListProvisionedConcurrencyConfigsInput{FunctionName: aws.String(p.Config.FunctionName)},
+		func(page *lambda.ListProvisionedConcurrencyConfigsOutput, _ bool) bool {
+			for _, pc := range page.ProvisionedConcurrencyConfigs {
+				if _, ok := qualifiedVersion(aws.StringValue(pc.FunctionArn)); ok {
+					versioned = append(versioned, pc)
+				} else {
+					others = append(others, pc)
+				}
+			}
+			return true
+		}); err != nil {
+		return nil, wrapError("list provisioned concurrency", err)
+	}
+	if len(versioned) == 1 {
+		add("provisioned_concurrency", aws.Int64Value(versioned[0].RequestedProvisionedConcurrentExecutions))
+	} else {
+		others = append(versioned, others...)
+	}
+	for _, pc := range others {
+		e.Notes = append(e.Notes, fmt.Sprintf("provisioned concurrency: %d on %s",
+			aws.Int64Value(pc.RequestedProvisionedConcurrentExecutions),
+			aws.StringValue(pc.FunctionArn)))
+	}
+	if err := svc.ListAliasesPagesWithContext(ctx,
+		&lambda.ListAliasesInput{FunctionName: aws.String(p.Config.FunctionName)},
+		func(page *lambda.ListAliasesOutput, _ bool) bool {
+			for _, alias := range page.Aliases {
+				note := fmt.Sprintf("alias %s: version %s",
+					aws.StringValue(alias.Name), aws.StringValue(alias.FunctionVersion))
+				if alias.RoutingConfig != nil {
+					weights := alias.RoutingConfig.AdditionalVersionWeights
+					for _, version := range slices.Sorted(maps.Keys(weights)) {
+						note += fmt.Sprintf(", %s at %g", version, aws.Float64Value(weights[version]))
+					}
+				}
+				e.Notes = append(e.Notes, note)
+			}
+			return true
+		}); err != nil {
+		return nil, wrapError("list aliases", err)
+	}
+
+	return e, nil
+}
+
+// render writes the export in the given format, with the notes as
+// comments.
+func (e *functionExport) render(format string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("# exported from " + e.Arn + "\n")
+	for _, note := range e.Notes {
+		b.WriteString("# " + note + "\n")
+	}
+
+	switch format {
+	case ExportFormatYAML:
+		settings := make(yaml.MapSlice, 0, len(e.Settings))
+		for _, s := range e.Settings {
+			settings = append(settings, yaml.MapItem{Key: s.Name, Value: escapeDrone(s.Value)})
+		}
+		content, err := yaml.Marshal(yaml.MapSlice{{Key: "settings", Value: settings}})
+		if err != nil {
+			return nil, err
+		}
+		b.Write(content)
+	case ExportFormatEnv:
+		for _, s := range e.Settings {
+			var value string
+			switch v := s.Value.(type) {
+			case string:
+				value = v
+			case int64:
+				value = strconv.FormatInt(v, 10)
+			case bool:
+				value = strconv.FormatBool(v)
+			case []string:
+				value = strings.Join(v, ",")
+			}
+			line, err := godotenv.Marshal(map[string]string{
+				"PLUGIN_" + strings.ToUpper(s.Name): value,
+			})
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(line + "\n")
+		}
+	default:
+		return nil, configError("invalid export format %q, must be yaml or env", format)
+	}
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+func (f *fakeLambda) GetFunctionWithContext(
+	_ aws.Context, input *lambda.GetFunctionInput, _ ...request.Option,
+) (*lambda.GetFunctionOutput, error) {
+	f.calls = append(f.calls, "GetFunction:"+aws.StringValue(input.Qualifier))
+	c := *f.config
+	output := &lambda.GetFunctionOutput{
+		Configuration: &c,
+		Code:          f.code,
+		Tags:          aws.StringMap(f.tags),
+	}
+	if f.reserved != nil {
+		output.Concurrency = &lambda.PutFunctionConcurrencyOutput{ReservedConcurrentExecutions: f.reserved}
+	}
+	return output, nil
+}
+
+func (f *fakeLambda) ListAliasesPagesWithContext(
+	_ aws.Context,
+	_ *lambda.ListAliasesInput,
+	fn func(*lambda.ListAliasesOutput, bool) bool,
+	_ ...request.Option,
+) error {
+	fn(&lambda.ListAliasesOutput{Aliases: f.aliases}, true)
+	return nil
+}
+
+func (f *fakeLambda) ListProvisionedConcurrencyConfigsPagesWithContext(
+	_ aws.Context,
+	_ *lambda.ListProvisionedConcurrencyConfigsInput,
+	fn func(*lambda.ListProvisionedConcurrencyConfigsOutput, bool) bool,
+	_ ...request.Option,
+) error {
+	fn(&lambda.ListProvisionedConcurrencyConfigsOutput{ProvisionedConcurrencyConfigs: f.provisioned}, true)
+	return nil
+}
+
+func newExportLambda() *fakeLambda {
+	svc := newFakeLambda()
+	svc.config.Runtime = aws.String(lambda.RuntimeProvidedAl2023)
+	svc.config.Handler = aws.String("bootstrap")
+	svc.config.MemorySize = aws.Int64(512)
+	svc.config.Timeout = aws.Int64(30)
+	svc.config.Description = aws.String("api for ${TEAM}")
+	svc.config.Architectures = aws.StringSlice([]string{"arm64"})
+	svc.config.Layers = []*lambda.Layer{{Arn: aws.String("arn:aws:lambda:us-east-1:123456789012:layer:otel:3")}}
+	svc.config.TracingConfig = &lambda.TracingConfigResponse{Mode: aws.String(lambda.TracingModePassThrough)}
+	svc.config.EphemeralStorage = &lambda.EphemeralStorage{Size: aws.Int64(minEphemeralStorage)}
+	svc.config.SnapStart = &lambda.SnapStartResponse{ApplyOn: aws.String(lambda.SnapStartApplyOnNone)}
+	svc.config.LoggingConfig = &lambda.LoggingConfig{
+		LogFormat:           aws.String(lambda.LogFormatJson),
+		ApplicationLogLevel: aws.String(lambda.ApplicationLogLevelInfo),
+		SystemLogLevel:      aws.String(lambda.SystemLogLevelWarn),
+		LogGroup:            aws.String("/aws/lambda/test"),
+	}
+	svc.config.Environment = &lambda.EnvironmentResponse{Variables: aws.StringMap(map[string]string{
+		"LOG_LEVEL":   "info",
+		"DB_PASSWORD": "s3cr3t",
+	})}
+	svc.tags = map[string]string{
+		"team":                          "payments",
+		managedTagPrefix + "commit-sha": "e5f9a8b7",
+		"aws:cloudformation:stack-name": "legacy",
+	}
+	svc.reserved = aws.Int64(20)
+	svc.aliases = []*lambda.AliasConfiguration{{
+		Name:            aws.String("live"),
+		FunctionVersion: aws.String("12"),
+		RoutingConfig: &lambda.AliasRoutingConfiguration{
+			AdditionalVersionWeights: aws.Float64Map(map[string]float64{"13": 0.1}),
+		},
+	}}
+	svc.provisioned = []*lambda.ProvisionedConcurrencyConfigListItem{{
+		FunctionArn:                              aws.String("arn:aws:lambda:us-east-1:123456789012:function:test:live"),
+		RequestedProvisionedConcurrentExecutions: aws.Int64(5),
+	}}
+	return svc
+}
+
+func TestPlugin_exportFunction(t *testing.T) {
+	p := &Plugin{Config: Config{FunctionName: "test", SecretPatterns: defaultSecretPatterns}}
+	e, err := p.exportFunction(context.Background(), newExportLambda(), RedactSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNotes := []string{
+		"environment values shown as ****** were redacted and must be replaced",
+		"provisioned concurrency: 5 on arn:aws:lambda:us-east-1:123456789012:function:test:live",
+		"alias live: version 12, 13 at 0.1",
+	}
+	if !reflect.DeepEqual(e.Notes, wantNotes) {
+		t.Errorf("notes = %q, want %q", e.Notes, wantNotes)
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		content, err := e.render(ExportFormatYAML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(content), "# exported from arn:aws:lambda:us-east-1:123456789012:function:test\n") {
+			t.Errorf("missing header in %s", content)
+		}
+
+		var step struct {
+			Settings map[string]any `yaml:"settings"`
+		}
+		if err := yaml.Unmarshal(content, &step); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]any{
+			"function_name": "test",
+			"runtime":       "provided.al2023",
+			"handler":       "bootstrap",
+			"memory_size":   512,
+			"timeout":       30,
+			"description":   "api for $$$${TEAM}",
+			"architectures": []any{"arm64"},
+			"layers":        []any{"arn:aws:lambda:us-east-1:123456789012:layer:otel:3"},
+			"environment":   []any{"DB_PASSWORD=******", "LOG_LEVEL=info"},
+			"tags":          []any{"team=payments"},
+
+			"reserved_concurrency": 20,
+
+			"function_log_format":   "JSON",
+			"application_log_level": "INFO",
+			"system_log_level":      "WARN",
+		}
+		if !reflect.DeepEqual(step.Settings, want) {
+			t.Errorf("settings = %v, want %v", step.Settings, want)
+		}
+	})
+
+	t.Run("env", func(t *testing.T) {
+		content, err := e.render(ExportFormatEnv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := godotenv.UnmarshalBytes(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{
+			"PLUGIN_FUNCTION_NAME": "test",
+			"PLUGIN_RUNTIME":       "provided.al2023",
+			"PLUGIN_HANDLER":       "bootstrap",
+			"PLUGIN_MEMORY_SIZE":   "512",
+			"PLUGIN_TIMEOUT":       "30",
+			"PLUGIN_DESCRIPTION":   "api for $${TEAM}",
+			"PLUGIN_ARCHITECTURES": "arm64",
+			"PLUGIN_LAYERS":        "arn:aws:lambda:us-east-1:123456789012:layer:otel:3",
+			"PLUGIN_ENVIRONMENT":   "DB_PASSWORD=******,LOG_LEVEL=info",
+			"PLUGIN_TAGS":          "team=payments",
+
+			"PLUGIN_RESERVED_CONCURRENCY": "20",
+
+			"PLUGIN_FUNCTION_LOG_FORMAT":   "JSON",
+			"PLUGIN_APPLICATION_LOG_LEVEL": "INFO",
+			"PLUGIN_SYSTEM_LOG_LEVEL":      "WARN",
+		}
+		if !reflect.DeepEqual(env, want) {
+			t.Errorf("env = %v, want %v", env, want)
+		}
+	})
+}
+
+func TestPlugin_exportFunction_redact(t *testing.T) {
+	tests := []struct {
+		redact   string
+		want     []string
+		wantKind ErrorKind
+	}{
+		{redact: RedactNone, want: []string{"DB_PASSWORD=s3cr3t", "LOG_LEVEL=info"}},
+		{redact: RedactSecrets, want: []string{"DB_PASSWORD=******", "LOG_LEVEL=info"}},
+		{redact: RedactAll, want: []string{"DB_PASSWORD=******", "LOG_LEVEL=******"}},
+		{redact: "some", wantKind: KindInvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.redact, func(t *testing.T) {
+			p := &Plugin{Config: Config{FunctionName: "test", SecretPatterns: defaultSecretPatterns}}
+			e, err := p.exportFunction(context.Background(), newExportLambda(), tt.redact)
+			if tt.wantKind != 0 {
+				if exitCode(err) != int(tt.wantKind) {
+					t.Fatalf("exportFunction() error = %v, want kind %v", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range e.Settings {
+				if s.Name == "environment" && !reflect.DeepEqual(s.Value, tt.want) {
+					t.Errorf("environment = %v, want %v", s.Value, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlugin_exportFunction_roundTrip(t *testing.T) {
+	svc := newExportLambda()
+	svc.config.Environment = &lambda.EnvironmentResponse{Variables: aws.StringMap(map[string]string{
+		"GREETING": "hello ${VISITOR}",
+		"PASSWORD": "pa$word$$1",
+	})}
+	wantDescription := "api for ${TEAM}"
+	wantEnvironment := []string{"GREETING=hello ${VISITOR}", "PASSWORD=pa$word$$1"}
+
+	p := &Plugin{Config: Config{FunctionName: "test"}}
+	e, err := p.exportFunction(context.Background(), svc, RedactNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check expands the deployed values like a deploy of the export does
+	check := func(t *testing.T, description string, environment []string) {
+		t.Helper()
+		got, err := p.expand(description)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != wantDescription {
+			t.Errorf("description = %q, want %q", got, wantDescription)
+		}
+		for i, value := range environment {
+			if environment[i], err = p.expand(value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(environment, wantEnvironment) {
+			t.Errorf("environment = %q, want %q", environment, wantEnvironment)
+		}
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		content, err := e.render(ExportFormatYAML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var step struct {
+			Settings struct {
+				Description string   `yaml:"description"`
+				Environment []string `yaml:"environment"`
+			} `yaml:"settings"`
+		}
+		if err := yaml.Unmarshal(content, &step); err != nil {
+			t.Fatal(err)
+		}
+
+		// Drone replaces $$ with $ in .drone.yml
+		unescape := strings.NewReplacer("$$", "$").Replace
+		environment := make([]string, 0, len(step.Settings.Environment))
+		for _, value := range step.Settings.Environment {
+			environment = append(environment, unescape(value))
+		}
+		check(t, unescape(step.Settings.Description), environment)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		content, err := e.render(ExportFormatEnv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := godotenv.UnmarshalBytes(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, env["PLUGIN_DESCRIPTION"], strings.Split(env["PLUGIN_ENVIRONMENT"], ","))
+	})
+}
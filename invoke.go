@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/urfave/cli/v2"
+)
+
+// maxClientContext is the size limit of the base64 encoded client context.
+const maxClientContext = 3583
+
+// invokeOptions describes a single invocation of the function.
+type invokeOptions struct {
+	Qualifier      string
+	Payload        []byte
+	InvocationType string
+	ClientContext  string
+}
+
+func invokeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "invoke",
+		Usage:     "Invoke a function, alias or version",
+		ArgsUsage: "[payload]",
+		Description: "The JSON payload is read from the argument, from --payload-file " +
+			"or from stdin when either is -.",
+		Flags: append([]cli.Flag{
+			functionNameFlag(),
+			&cli.StringFlag{
+				Name:    "qualifier",
+				Usage:   "The version or alias to invoke",
+				EnvVars: []string{"PLUGIN_QUALIFIER", "QUALIFIER", "INPUT_QUALIFIER"},
+			},
+			&cli.StringFlag{
+				Name:    "payload-file",
+				Usage:   "Read the JSON payload from this file, - for stdin",
+				EnvVars: []string{"PLUGIN_PAYLOAD_FILE", "PAYLOAD_FILE", "INPUT_PAYLOAD_FILE"},
+			},
+			&cli.StringFlag{
+				Name:    "invocation-type",
+				Usage:   "RequestResponse, Event or DryRun",
+				Value:   lambda.InvocationTypeRequestResponse,
+				EnvVars: []string{"PLUGIN_INVOCATION_TYPE", "INVOCATION_TYPE", "INPUT_INVOCATION_TYPE"},
+			},
+			&cli.StringFlag{
+				Name:    "client-context",
+				Usage:   "JSON client context passed to the function",
+				EnvVars: []string{"PLUGIN_CLIENT_CONTEXT", "CLIENT_CONTEXT", "INPUT_CLIENT_CONTEXT"},
+			},
+		}, awsFlags()...),
+		Action: func(c *cli.Context) error {
+			p, err := commandPlugin(c)
+			if err != nil {
+				return err
+			}
+
+			payload, err := readPayload(c.Args().First(), c.String("payload-file"), os.Stdin)
+			if err != nil {
+				return err
+			}
+
+			sess, err := p.newSession()
+			if err != nil {
+				return wrapError("create session", err)
+			}
+
+			return p.invoke(c.Context, lambda.New(sess), invokeOptions{
+				Qualifier:      c.String("qualifier"),
+				Payload:        payload,
+				InvocationType: c.String("invocation-type"),
+				ClientContext:  c.String("client-context"),
+			}, os.Stdout, os.Stderr)
+		},
+	}
+}
+
+// readPayload returns the payload given as argument or read from file,
+// where - reads stdin.
+func readPayload(arg, file string, stdin io.Reader) ([]byte, error) {
+	if arg != "" && file != "" {
+		return nil, configError("payload argument and payload file are mutually exclusive")
+	}
+
+	var payload []byte
+	switch {
+	case arg == "-" || file == "-":
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, configError("read payload from stdin: %w", err)
+		}
+		payload = b
+	case file != "":
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, configError("read payload file: %w", err)
+		}
+		payload = b
+	default:
+		payload = []byte(arg)
+	}
+
+	if len(payload) > 0 && !json.Valid(payload) {
+		return nil, configError("payload is not valid JSON")
+	}
+
+	return payload, nil
+}
+
+func (o invokeOptions) input(functionName string) (*lambda.InvokeInput, error) {
+	if !slices.Contains(lambda.InvocationType_Values(), o.InvocationType) {
+		return nil, configError(
+			"invalid invocation type %q, must be one of %v",
+			o.InvocationType, lambda.InvocationType_Values(),
+		)
+	}
+
+	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		InvocationType: aws.String(o.InvocationType),
+		Payload:        o.Payload,
+	}
+	if o.Qualifier != "" {
+		input.SetQualifier(o.Qualifier)
+	}
+	// the log tail is only returned for synchronous invocations
+	if o.InvocationType == lambda.InvocationTypeRequestResponse {
+		input.SetLogType(lambda.LogTypeTail)
+	}
+	if o.ClientContext != "" {
+		if !json.Valid([]byte(o.ClientContext)) {
+			return nil, configError("client context is not valid JSON")
+		}
+		encoded := base64.StdEncoding.EncodeToString([]byte(o.ClientContext))
+		if len(encoded) > maxClientContext {
+			return nil, configError("client context exceeds %d bytes when encoded", maxClientContext)
+		}
+		input.SetClientContext(encoded)
+	}
+
+	return input, nil
+}
+
+// invoke invokes the function and writes the response payload to out and
+// the decoded log tail to logs. A function error is returned as an
+// *Error of KindFunctionError.
+func (p *Plugin) invoke(
+	ctx context.Context, svc lambdaiface.LambdaAPI, opts invokeOptions, out, logs io.Writer,
+) error {
+	if p.Config.FunctionName == "" {
+		return configError("missing lambda function name")
+	}
+
+	input, err := opts.input(p.Config.FunctionName)
+	if err != nil {
+		return err
+	}
+
+	logger := p.logger().With("phase", "invoke")
+	logger.Info("invoke function",
+		"qualifier", opts.Qualifier,
+		"invocation_type", opts.InvocationType,
+	)
+	output, err := svc.InvokeWithContext(ctx, input)
+	if err != nil {
+		return wrapError("invoke function", err)
+	}
+	logger.Info("function invoked",
+		"status_code", aws.Int64Value(output.StatusCode),
+		"executed_version", aws.StringValue(output.ExecutedVersion),
+	)
+
+	if output.LogResult != nil {
+		tail, err := base64.StdEncoding.DecodeString(aws.StringValue(output.LogResult))
+		if err != nil {
+			logger.Warn("unable to decode log tail", "error", err)
+		} else if _, err := logs.Write(tail); err != nil {
+			return err
+		}
+	}
+
+	if len(output.Payload) > 0 {
+		if _, err := fmt.Fprintln(out, string(output.Payload)); err != nil {
+			return err
+		}
+	}
+
+	if output.FunctionError != nil {
+		return &Error{
+			Kind: KindFunctionError,
+			Op:   "invoke function",
+			Err:  errors.New(aws.StringValue(output.FunctionError)),
+			Hint: "see the response and the log tail",
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func (f *fakeLambda) InvokeWithContext(
+	_ aws.Context, input *lambda.InvokeInput, _ ...request.Option,
+) (*lambda.InvokeOutput, error) {
+	f.invokeInput = input
+	return f.invokeOutput, nil
+}
+
+func Test_readPayload(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "payload.json")
+	if err := os.WriteFile(file, []byte(`{"from":"file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		arg     string
+		file    string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "argument", arg: `{"from":"arg"}`, want: `{"from":"arg"}`},
+		{name: "file", file: file, want: `{"from":"file"}`},
+		{name: "stdin argument", arg: "-", stdin: `{"from":"stdin"}`, want: `{"from":"stdin"}`},
+		{name: "stdin file", file: "-", stdin: `[1, 2]`, want: `[1, 2]`},
+		{name: "missing file", file: filepath.Join(dir, "missing.json"), wantErr: true},
+		{name: "invalid json", arg: `{"from":`, wantErr: true},
+		{name: "argument and file", arg: `{}`, file: file, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readPayload(tt.arg, tt.file, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readPayload() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlugin_invoke(t *testing.T) {
+	tail := base64.StdEncoding.EncodeToString([]byte("START RequestId: 1\nEND RequestId: 1\n"))
+
+	tests := []struct {
+		name     string
+		opts     invokeOptions
+		output   *lambda.InvokeOutput
+		wantOut  string
+		wantLogs string
+		wantKind ErrorKind
+	}{
+		{
+			name: "request response",
+			opts: invokeOptions{
+				Qualifier:      "live",
+				Payload:        []byte(`{"job":"migrate"}`),
+				InvocationType: lambda.InvocationTypeRequestResponse,
+				ClientContext:  `{"custom":{"build":"42"}}`,
+			},
+			output: &lambda.InvokeOutput{
+				StatusCode:      aws.Int64(200),
+				ExecutedVersion: aws.String("3"),
+				LogResult:       aws.String(tail),
+				Payload:         []byte(`{"migrated":12}`),
+			},
+			wantOut:  "{\"migrated\":12}\n",
+			wantLogs: "START RequestId: 1\nEND RequestId: 1\n",
+		},
+		{
+			name: "event",
+			opts: invokeOptions{InvocationType: lambda.InvocationTypeEvent},
+			output: &lambda.InvokeOutput{
+				StatusCode: aws.Int64(202),
+			},
+		},
+		{
+			name: "function error",
+			opts: invokeOptions{InvocationType: lambda.InvocationTypeRequestResponse},
+			output: &lambda.InvokeOutput{
+				StatusCode:    aws.Int64(200),
+				FunctionError: aws.String("Unhandled"),
+				Payload:       []byte(`{"errorMessage":"boom"}`),
+			},
+			wantOut:  "{\"errorMessage\":\"boom\"}\n",
+			wantKind: KindFunctionError,
+		},
+		{
+			name:     "invalid invocation type",
+			opts:     invokeOptions{InvocationType: "Sync"},
+			wantKind: KindInvalidConfig,
+		},
+		{
+			name: "invalid client context",
+			opts: invokeOptions{
+				InvocationType: lambda.InvocationTypeDryRun,
+				ClientContext:  "build=42",
+			},
+			wantKind: KindInvalidConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeLambda()
+			svc.invokeOutput = tt.output
+
+			var out, logs bytes.Buffer
+			p := &Plugin{Config: Config{FunctionName: "test"}}
+			err := p.invoke(context.Background(), svc, tt.opts, &out, &logs)
+			if tt.wantKind != 0 {
+				var e *Error
+				if !errors.As(err, &e) || e.Kind != tt.wantKind {
+					t.Fatalf("invoke() error = %v, want kind %v", err, tt.wantKind)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("output = %q, want %q", out.String(), tt.wantOut)
+			}
+			if logs.String() != tt.wantLogs {
+				t.Errorf("logs = %q, want %q", logs.String(), tt.wantLogs)
+			}
+			if tt.output == nil {
+				return
+			}
+
+			input := svc.invokeInput
+			if got := aws.StringValue(input.Qualifier); got != tt.opts.Qualifier {
+				t.Errorf("qualifier = %q, want %q", got, tt.opts.Qualifier)
+			}
+			wantLogType := ""
+			if tt.opts.InvocationType == lambda.InvocationTypeRequestResponse {
+				wantLogType = lambda.LogTypeTail
+			}
+			if got := aws.StringValue(input.LogType); got != wantLogType {
+				t.Errorf("log type = %q, want %q", got, wantLogType)
+			}
+			if tt.opts.ClientContext != "" {
+				decoded, _ := base64.StdEncoding.DecodeString(aws.StringValue(input.ClientContext))
+				if string(decoded) != tt.opts.ClientContext {
+					t.Errorf("client context = %q, want %q", decoded, tt.opts.ClientContext)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Log formats.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// newLogger returns a leveled logger writing in the given format. Debug
+// messages are only written when debug is true.
+func newLogger(w io.Writer, format string, debug bool) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "", LogFormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+
+	return nil, configError("invalid log format %q", format)
+}
+
+// logger returns the logger of the plugin, annotated with the function
+// and region of the deploy.
+func (p *Plugin) logger() *slog.Logger {
+	if p.log == nil {
+		p.log = slog.Default()
+	}
+	return p.log.With(
+		"function", p.Config.FunctionName,
+		"region", p.Config.Region,
+	)
+}
+
+// phase logs the start of a deploy phase and starts its span. The
+// returned func ends the span and logs the duration of the phase.
+func (p *Plugin) phase(
+	ctx context.Context, name, msg string, attrs ...attribute.KeyValue,
+) (context.Context, func(error)) {
+	logger := p.logger().With("phase", name)
+	logger.Info(msg)
+
+	ctx, span := p.startSpan(ctx, strings.ReplaceAll(msg, " ", "_"), attrs...)
+	start := time.Now()
+	return ctx, func(err error) {
+		endSpan(span, err)
+		duration := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			logger.Warn(msg+" failed", "duration", duration)
+			return
+		}
+		logger.Info(msg+" done", "duration", duration)
+	}
+}
+
+// waiterProgress logs every poll of a waiter, with the state reported
+// by the function and the time spent waiting so far.
+func (p *Plugin) waiterProgress(msg string) request.WaiterOption {
+	logger := p.logger().With("phase", "wait")
+	start := time.Now()
+	attempt := 0
+
+	return request.WithWaiterRequestOptions(func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			attempt++
+			args := []any{
+				"attempt", attempt,
+				"max_attempts", p.Config.MaxAttempts,
+				"duration", time.Since(start).Round(time.Millisecond),
+			}
+			if output, ok := r.Data.(*lambda.GetFunctionOutput); ok && output.Configuration != nil {
+				args = append(args,
+					"state", aws.StringValue(output.Configuration.State),
+					"last_update_status", aws.StringValue(output.Configuration.LastUpdateStatus),
+				)
+			}
+			logger.Info(msg, args...)
+		})
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_newLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, LogFormatJSON, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Plugin{
+		Config: Config{FunctionName: "api", Region: "eu-west-1"},
+		log:    logger.With("version", "1.2.3"),
+	}
+	p.logger().Debug("hidden")
+	_, done := p.phase(context.Background(), "code", "update function code")
+	done(nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{
+		"level":    "INFO",
+		"msg":      "update function code done",
+		"function": "api",
+		"region":   "eu-west-1",
+		"phase":    "code",
+		"version":  "1.2.3",
+	} {
+		if entry[key] != want {
+			t.Errorf("%s = %v, want %v", key, entry[key], want)
+		}
+	}
+	if _, ok := entry["duration"]; !ok {
+		t.Errorf("duration is missing: %v", entry)
+	}
+}
+
+func Test_newLogger_format(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, LogFormatText, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("shown", "phase", "status")
+	if !strings.Contains(buf.String(), "level=DEBUG msg=shown phase=status") {
+		t.Errorf("unexpected text output: %s", buf.String())
+	}
+
+	if _, err := newLogger(&buf, "xml", false); err == nil {
+		t.Errorf("newLogger() expected error for an invalid format")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// retentionDays are the retention periods CloudWatch Logs accepts.
+var retentionDays = []int64{
+	1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545,
+	731, 1096, 1827, 2192, 2557, 2922, 3288, 3653,
+}
+
+func (p Plugin) validateLogging() error {
+	format := p.Config.FunctionLogFormat
+	if format != "" && !slices.Contains(lambda.LogFormat_Values(), format) {
+		return configError("invalid function log format %q, must be one of %v", format, lambda.LogFormat_Values())
+	}
+
+	levels := []struct {
+		name, value string
+		values      []string
+	}{
+		{"application log level", p.Config.ApplicationLogLevel, lambda.ApplicationLogLevel_Values()},
+		{"system log level", p.Config.SystemLogLevel, lambda.SystemLogLevel_Values()},
+	}
+	for _, level := range levels {
+		if level.value == "" {
+			continue
+		}
+		if !slices.Contains(level.values, level.value) {
+			return configError("invalid %s %q, must be one of %v", level.name, level.value, level.values)
+		}
+		// Lambda only filters JSON logs by level
+		if format != lambda.LogFormatJson {
+			return configError("%s requires function log format %q", level.name, lambda.LogFormatJson)
+		}
+	}
+
+	if days := p.Config.LogRetentionDays; days != 0 {
+		if !p.Config.CreateLogGroup {
+			return configError("log retention days requires create log group")
+		}
+		if !slices.Contains(retentionDays, days) {
+			return configError("invalid log retention days %d, must be one of %v", days, retentionDays)
+		}
+	}
+
+	return nil
+}
+
+// loggingConfig returns the logging config for the settings, or nil if
+// none is set.
+func (p Plugin) loggingConfig() *lambda.LoggingConfig {
+	c := &lambda.LoggingConfig{}
+	if p.Config.FunctionLogFormat != "" {
+		c.SetLogFormat(p.Config.FunctionLogFormat)
+	}
+	if p.Config.ApplicationLogLevel != "" {
+		c.SetApplicationLogLevel(p.Config.ApplicationLogLevel)
+	}
+	if p.Config.SystemLogLevel != "" {
+		c.SetSystemLogLevel(p.Config.SystemLogLevel)
+	}
+	if p.Config.LogGroup != "" {
+		c.SetLogGroup(p.Config.LogGroup)
+	}
+
+	if *c == (lambda.LoggingConfig{}) {
+		return nil
+	}
+	return c
+}
+
+// ensureLogGroup creates the log group of the function unless it exists
+// and sets its retention policy. Lambda creates a missing log group on
+// the first invocation, but without a retention policy.
+func (p *Plugin) ensureLogGroup(ctx context.Context, logs cloudwatchlogsiface.CloudWatchLogsAPI) error {
+	if !p.Config.CreateLogGroup || p.Config.DryRun {
+		return nil
+	}
+
+	group := p.Config.LogGroup
+	if group == "" {
+		group = "/aws/lambda/" + p.Config.FunctionName
+	}
+
+	ctx, done := p.phase(ctx, "log_group", "create log group")
+	err := p.createLogGroup(ctx, logs, group)
+	done(err)
+
+	return err
+}
+
+func (p *Plugin) createLogGroup(ctx context.Context, logs cloudwatchlogsiface.CloudWatchLogsAPI, group string) error {
+	logger := p.logger().With("phase", "log_group", "log_group", group)
+
+	_, err := logs.CreateLogGroupWithContext(ctx, &cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(group),
+	})
+	var aerr awserr.Error
+	switch {
+	case errors.As(err, &aerr) && aerr.Code() == cloudwatchlogs.ErrCodeResourceAlreadyExistsException:
+		logger.Info("log group exists")
+	case err != nil:
+		return wrapError("create log group", err)
+	default:
+		logger.Info("log group created")
+	}
+
+	if p.Config.LogRetentionDays == 0 {
+		return nil
+	}
+
+	_, err = logs.PutRetentionPolicyWithContext(ctx, &cloudwatchlogs.PutRetentionPolicyInput{
+		LogGroupName:    aws.String(group),
+		RetentionInDays: aws.Int64(p.Config.LogRetentionDays),
+	})
+	if err != nil {
+		return wrapError("put retention policy", err)
+	}
+	logger.Info("log retention set", "retention_days", p.Config.LogRetentionDays)
+
+	return nil
+}
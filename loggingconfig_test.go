@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// newLogGroupStub serves CreateLogGroup and PutRetentionPolicy and
+// records every call as "Operation:group[:days]". Creating one of the
+// existing groups fails like CloudWatch Logs does.
+func newLogGroupStub(t *testing.T, existing []string, calls *[]string) *cloudwatchlogs.CloudWatchLogs {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			LogGroupName    string `json:"logGroupName"`
+			RetentionInDays int64  `json:"retentionInDays"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Error(err)
+		}
+
+		op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+		call := op + ":" + input.LogGroupName
+		if input.RetentionInDays != 0 {
+			call += fmt.Sprintf(":%d", input.RetentionInDays)
+		}
+		*calls = append(*calls, call)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if op == "CreateLogGroup" && slices.Contains(existing, input.LogGroupName) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceAlreadyExistsException","message":"log group exists"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	return cloudwatchlogs.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+}
+
+func TestPlugin_ensureLogGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		existing  []string
+		wantCalls []string
+	}{
+		{
+			name:   "disabled",
+			config: Config{LogGroup: "/app/api"},
+		},
+		{
+			name:      "default log group",
+			config:    Config{CreateLogGroup: true},
+			wantCalls: []string{"CreateLogGroup:/aws/lambda/test"},
+		},
+		{
+			name:   "custom log group with retention",
+			config: Config{CreateLogGroup: true, LogGroup: "/app/api", LogRetentionDays: 30},
+			wantCalls: []string{
+				"CreateLogGroup:/app/api",
+				"PutRetentionPolicy:/app/api:30",
+			},
+		},
+		{
+			name:     "existing log group",
+			config:   Config{CreateLogGroup: true, LogGroup: "/app/api", LogRetentionDays: 14},
+			existing: []string{"/app/api"},
+			wantCalls: []string{
+				"CreateLogGroup:/app/api",
+				"PutRetentionPolicy:/app/api:14",
+			},
+		},
+		{
+			name:   "dry run",
+			config: Config{CreateLogGroup: true, DryRun: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			logs := newLogGroupStub(t, tt.existing, &calls)
+			tt.config.FunctionName = "test"
+			p := &Plugin{Config: tt.config}
+
+			if err := p.ensureLogGroup(context.Background(), logs); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPlugin_validateLogging(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "text", config: Config{FunctionLogFormat: lambda.LogFormatText}},
+		{name: "json with levels", config: Config{
+			FunctionLogFormat:   lambda.LogFormatJson,
+			ApplicationLogLevel: lambda.ApplicationLogLevelDebug,
+			SystemLogLevel:      lambda.SystemLogLevelWarn,
+		}},
+		{name: "unknown format", config: Config{FunctionLogFormat: "XML"}, wantErr: true},
+		{name: "unknown level", config: Config{
+			FunctionLogFormat:   lambda.LogFormatJson,
+			ApplicationLogLevel: "VERBOSE",
+		}, wantErr: true},
+		{name: "level without json", config: Config{SystemLogLevel: lambda.SystemLogLevelInfo}, wantErr: true},
+		{name: "retention", config: Config{CreateLogGroup: true, LogRetentionDays: 90}},
+		{name: "invalid retention", config: Config{CreateLogGroup: true, LogRetentionDays: 10}, wantErr: true},
+		{name: "retention without create", config: Config{LogRetentionDays: 90}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Plugin{Config: tt.config}.validateLogging()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateLogging() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && exitCode(err) != int(KindInvalidConfig) {
+				t.Errorf("exit code = %d, want %d", exitCode(err), KindInvalidConfig)
+			}
+		})
+	}
+}
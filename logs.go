@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/urfave/cli/v2"
+)
+
+// logsPollInterval is the delay between two polls when following logs.
+var logsPollInterval = 2 * time.Second
+
+// logsOptions selects the log events to show.
+type logsOptions struct {
+	Since   time.Duration
+	Filter  string
+	Version string
+	Follow  bool
+}
+
+func logsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "logs",
+		Usage: "Show or follow the function's CloudWatch logs",
+		Flags: append([]cli.Flag{
+			functionNameFlag(),
+			&cli.DurationFlag{
+				Name:    "since",
+				Usage:   "Show events newer than this duration",
+				Value:   10 * time.Minute,
+				EnvVars: []string{"PLUGIN_SINCE", "SINCE", "INPUT_SINCE"},
+			},
+			&cli.StringFlag{
+				Name:    "filter",
+				Usage:   "CloudWatch Logs filter pattern",
+				EnvVars: []string{"PLUGIN_FILTER", "FILTER", "INPUT_FILTER"},
+			},
+			&cli.StringFlag{
+				Name:    "version",
+				Usage:   "Only show events of this function version, e.g. $LATEST or 42",
+				EnvVars: []string{"PLUGIN_LOGS_VERSION", "LOGS_VERSION", "INPUT_LOGS_VERSION"},
+			},
+			&cli.StringFlag{
+				Name:    "result-file",
+				Usage:   "Only show events of the version in this deploy result file",
+				EnvVars: []string{"PLUGIN_RESULT_FILE", "RESULT_FILE", "INPUT_RESULT_FILE"},
+			},
+			&cli.BoolFlag{
+				Name:    "follow",
+				Aliases: []string{"f"},
+				Usage:   "Keep polling for new events until interrupted",
+				EnvVars: []string{"PLUGIN_FOLLOW", "FOLLOW", "INPUT_FOLLOW"},
+			},
+		}, awsFlags()...),
+		Action: func(c *cli.Context) error {
+			p, err := commandPlugin(c)
+			if err != nil {
+				return err
+			}
+
+			version := c.String("version")
+			if version == "" && c.String("result-file") != "" {
+				if version, err = resultVersion(c.String("result-file")); err != nil {
+					return err
+				}
+			}
+
+			sess, err := p.newSession()
+			if err != nil {
+				return wrapError("create session", err)
+			}
+
+			return p.tailLogs(c.Context, lambda.New(sess), cloudwatchlogs.New(sess), logsOptions{
+				Since:   c.Duration("since"),
+				Filter:  c.String("filter"),
+				Version: version,
+				Follow:  c.Bool("follow"),
+			}, os.Stdout)
+		},
+	}
+}
+
+// resultVersion returns the function version from a deploy result file.
+func resultVersion(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", configError("read result file: %w", err)
+	}
+
+	var r Result
+	if err := json.Unmarshal(content, &r); err != nil {
+		return "", configError("parse result file: %w", err)
+	}
+
+	return r.Version, nil
+}
+
+// logGroup returns the log group of the function, which is set by its
+// logging config or defaults to /aws/lambda/<function name>.
+func (p *Plugin) logGroup(ctx context.Context, svc lambdaiface.LambdaAPI) (string, error) {
+	c, err := svc.GetFunctionConfigurationWithContext(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+	})
+	if err != nil {
+		return "", wrapError("get function configuration", err)
+	}
+
+	if c.LoggingConfig != nil && aws.StringValue(c.LoggingConfig.LogGroup) != "" {
+		return aws.StringValue(c.LoggingConfig.LogGroup), nil
+	}
+
+	return "/aws/lambda/" + aws.StringValue(c.FunctionName), nil
+}
+
+// matches reports whether the stream belongs to the selected version.
+// Lambda names the streams YYYY/MM/DD/[version]id.
+func (o logsOptions) matches(stream string) bool {
+	return o.Version == "" || strings.Contains(stream, "["+o.Version+"]")
+}
+
+// tailLogs writes the recent log events of the function to w. When
+// following, it polls for new events until ctx is canceled.
+func (p *Plugin) tailLogs(
+	ctx context.Context,
+	svc lambdaiface.LambdaAPI,
+	logs cloudwatchlogsiface.CloudWatchLogsAPI,
+	opts logsOptions,
+	w io.Writer,
+) error {
+	if p.Config.FunctionName == "" {
+		return configError("missing lambda function name")
+	}
+
+	group, err := p.logGroup(ctx, svc)
+	if err != nil {
+		return err
+	}
+	p.logger().Info("show log events",
+		"phase", "logs",
+		"log_group", group,
+		"since", opts.Since,
+		"version", opts.Version,
+	)
+
+	start := time.Now().Add(-opts.Since).UnixMilli()
+	// events at the start time are returned again by the next poll
+	seen := make(map[string]int64)
+	for {
+		input := &cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName: aws.String(group),
+			StartTime:    aws.Int64(start),
+		}
+		if opts.Filter != "" {
+			input.SetFilterPattern(opts.Filter)
+		}
+
+		var writeErr error
+		err := logs.FilterLogEventsPagesWithContext(ctx, input,
+			func(page *cloudwatchlogs.FilterLogEventsOutput, _ bool) bool {
+				for _, e := range page.Events {
+					id, ts := aws.StringValue(e.EventId), aws.Int64Value(e.Timestamp)
+					if _, ok := seen[id]; ok || !opts.matches(aws.StringValue(e.LogStreamName)) {
+						continue
+					}
+					seen[id] = ts
+					start = max(start, ts)
+
+					_, writeErr = fmt.Fprintln(w,
+						time.UnixMilli(ts).UTC().Format(time.RFC3339Nano),
+						strings.TrimRight(aws.StringValue(e.Message), "\n"),
+					)
+					if writeErr != nil {
+						return false
+					}
+				}
+				return true
+			})
+		if writeErr != nil {
+			return writeErr
+		}
+		if err != nil {
+			if opts.Follow && errors.Is(ctx.Err(), context.Canceled) {
+				return nil
+			}
+			return logsError(group, err)
+		}
+
+		if !opts.Follow {
+			return nil
+		}
+		for id, ts := range seen {
+			if ts < start {
+				delete(seen, id)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logsPollInterval):
+		}
+	}
+}
+
+func logsError(group string, err error) error {
+	err = wrapError("filter log events", err)
+
+	var e *Error
+	if errors.As(err, &e) && e.Kind == KindNotFound {
+		e.Hint = fmt.Sprintf("log group %s does not exist, the function may not have been invoked yet", group)
+	}
+
+	return err
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// logsStub is a local stand-in for CloudWatch Logs. Every poll returns
+// the next batch of events, split into pages of one event.
+type logsStub struct {
+	mu       sync.Mutex
+	groups   map[string][][]map[string]any
+	requests []map[string]any
+	polls    int
+
+	// onPoll runs after a poll returned its last page
+	onPoll func(polls int)
+}
+
+func newLogsStub(t *testing.T, stub *logsStub) *cloudwatchlogs.CloudWatchLogs {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); target != "Logs_20140328.FilterLogEvents" {
+			t.Errorf("unexpected target %q", target)
+		}
+		var input map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Error(err)
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.requests = append(stub.requests, input)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		group, _ := input["logGroupName"].(string)
+		batches, ok := stub.groups[group]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"log group does not exist"}`))
+			return
+		}
+
+		var events []map[string]any
+		if stub.polls < len(batches) {
+			events = batches[stub.polls]
+		}
+		// page through the batch one event at a time
+		page := 0
+		if token, ok := input["nextToken"].(string); ok {
+			page = len(token)
+		}
+		output := map[string]any{"events": []any{}}
+		if page < len(events) {
+			output["events"] = events[page : page+1]
+		}
+		if page+1 < len(events) {
+			output["nextToken"] = strings.Repeat("x", page+1)
+		} else {
+			stub.polls++
+			if stub.onPoll != nil {
+				stub.onPoll(stub.polls)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(output)
+	}))
+	t.Cleanup(srv.Close)
+
+	return cloudwatchlogs.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+}
+
+func logEvent(id, stream, message string, ts time.Time) map[string]any {
+	return map[string]any{
+		"eventId":       id,
+		"logStreamName": stream,
+		"message":       message,
+		"timestamp":     ts.UnixMilli(),
+	}
+}
+
+func TestPlugin_tailLogs(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := [][]map[string]any{{
+		logEvent("1", "2026/01/02/[$LATEST]a", "START RequestId: 1\n", ts),
+		logEvent("2", "2026/01/02/[7]b", "hello from 7\n", ts.Add(time.Second)),
+		logEvent("3", "2026/01/02/[8]c", "hello from 8\n", ts.Add(2*time.Second)),
+	}}
+
+	tests := []struct {
+		name      string
+		logGroup  string
+		opts      logsOptions
+		wantGroup string
+		want      []string
+		wantKind  ErrorKind
+	}{
+		{
+			name:      "default log group",
+			opts:      logsOptions{Since: time.Hour, Filter: "hello"},
+			wantGroup: "/aws/lambda/test",
+			want: []string{
+				"2026-01-02T03:04:05Z START RequestId: 1",
+				"2026-01-02T03:04:06Z hello from 7",
+				"2026-01-02T03:04:07Z hello from 8",
+			},
+		},
+		{
+			name:      "custom log group and version",
+			logGroup:  "/shared/functions",
+			opts:      logsOptions{Since: time.Hour, Version: "7"},
+			wantGroup: "/shared/functions",
+			want:      []string{"2026-01-02T03:04:06Z hello from 7"},
+		},
+		{
+			name:      "missing log group",
+			logGroup:  "/missing",
+			opts:      logsOptions{Since: time.Hour},
+			wantGroup: "/missing",
+			wantKind:  KindNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeLambda()
+			if tt.logGroup != "" {
+				svc.config.LoggingConfig = &lambda.LoggingConfig{LogGroup: aws.String(tt.logGroup)}
+			}
+			stub := &logsStub{groups: map[string][][]map[string]any{
+				"/aws/lambda/test":  events,
+				"/shared/functions": events,
+			}}
+			logs := newLogsStub(t, stub)
+
+			var out bytes.Buffer
+			p := &Plugin{Config: Config{FunctionName: "test"}}
+			err := p.tailLogs(context.Background(), svc, logs, tt.opts, &out)
+			if tt.wantKind != 0 {
+				var e *Error
+				if !errors.As(err, &e) || e.Kind != tt.wantKind {
+					t.Fatalf("tailLogs() error = %v, want kind %v", err, tt.wantKind)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			if out.Len() > 0 {
+				got = strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+
+			req := stub.requests[0]
+			if req["logGroupName"] != tt.wantGroup {
+				t.Errorf("log group = %v, want %v", req["logGroupName"], tt.wantGroup)
+			}
+			if tt.opts.Filter != "" && req["filterPattern"] != tt.opts.Filter {
+				t.Errorf("filter pattern = %v, want %v", req["filterPattern"], tt.opts.Filter)
+			}
+			since := time.Since(time.UnixMilli(int64(req["startTime"].(float64))))
+			if since < tt.opts.Since || since > tt.opts.Since+time.Minute {
+				t.Errorf("start time is %v ago, want %v", since, tt.opts.Since)
+			}
+		})
+	}
+}
+
+func TestPlugin_tailLogs_follow(t *testing.T) {
+	logsPollInterval = time.Millisecond
+	t.Cleanup(func() { logsPollInterval = 2 * time.Second })
+
+	// CloudWatch Logs only returns events after the start time
+	ts := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stub := &logsStub{
+		groups: map[string][][]map[string]any{
+			"/aws/lambda/test": {
+				{logEvent("1", "s", "first", ts)},
+				// the event at the start time is returned again
+				{logEvent("1", "s", "first", ts), logEvent("2", "s", "second", ts.Add(time.Second))},
+				{},
+			},
+		},
+		onPoll: func(polls int) {
+			if polls == 3 {
+				cancel()
+			}
+		},
+	}
+	logs := newLogsStub(t, stub)
+
+	var out bytes.Buffer
+	p := &Plugin{Config: Config{FunctionName: "test"}}
+	if err := p.tailLogs(ctx, newFakeLambda(), logs, logsOptions{Since: time.Hour, Follow: true}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := ts.Format(time.RFC3339) + " first\n" + ts.Add(time.Second).Format(time.RFC3339) + " second\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if got := stub.requests[len(stub.requests)-1]["startTime"]; got != float64(ts.Add(time.Second).UnixMilli()) {
+		t.Errorf("last start time = %v, want the newest event", got)
+	}
+}
+
+func Test_resultVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.json")
+	if err := os.WriteFile(path, []byte(`{"function_name":"api","version":"12"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := resultVersion(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "12" {
+		t.Errorf("resultVersion() = %q, want 12", got)
+	}
+
+	if _, err := resultVersion(filepath.Join(t.TempDir(), "missing.json")); exitCode(err) != int(KindInvalidConfig) {
+		t.Errorf("resultVersion() error = %v, want invalid config", err)
+	}
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/joho/godotenv"
 	"github.com/urfave/cli/v2"
 )
@@ -36,45 +37,11 @@ func main() {
 		},
 	}
 	app.Action = run
+	// errors are logged and mapped to exit codes below
+	app.ExitErrHandler = func(*cli.Context, error) {}
 	app.Version = Version
-	app.Flags = []cli.Flag{
-		&cli.StringFlag{
-			Name:    "region",
-			Usage:   "AWS Region",
-			EnvVars: []string{"PLUGIN_REGION", "PLUGIN_AWS_REGION", "INPUT_AWS_REGION"},
-		},
-		&cli.StringFlag{
-			Name:  "access-key",
-			Usage: "AWS ACCESS KEY",
-			EnvVars: []string{
-				"PLUGIN_ACCESS_KEY",
-				"PLUGIN_AWS_ACCESS_KEY_ID",
-				"INPUT_AWS_ACCESS_KEY_ID",
-			},
-		},
-		&cli.StringFlag{
-			Name:  "secret-key",
-			Usage: "AWS SECRET KEY",
-			EnvVars: []string{
-				"PLUGIN_SECRET_KEY",
-				"PLUGIN_AWS_SECRET_ACCESS_KEY",
-				"INPUT_AWS_SECRET_ACCESS_KEY",
-			},
-		},
-		&cli.StringFlag{
-			Name:  "session-token",
-			Usage: "AWS Session token",
-			EnvVars: []string{
-				"PLUGIN_SESSION_TOKEN",
-				"PLUGIN_AWS_SESSION_TOKEN",
-				"INPUT_AWS_SESSION_TOKEN",
-			},
-		},
-		&cli.StringFlag{
-			Name:    "aws-profile",
-			Usage:   "AWS profile",
-			EnvVars: []string{"PLUGIN_PROFILE", "PLUGIN_AWS_PROFILE", "INPUT_AWS_PROFILE"},
-		},
+	app.Commands = commands()
+	app.Flags = append(awsFlags(), []cli.Flag{
 		&cli.StringFlag{
 			Name:    "function-name",
 			Usage:   "AWS lambda function name",
@@ -85,6 +52,12 @@ func main() {
 			Usage:   "Only update the function if the revision ID matches the ID that's specified.",
 			EnvVars: []string{"PLUGIN_REVERSION_ID", "REVERSION_ID", "INPUT_REVERSION_ID"},
 		},
+		&cli.BoolFlag{
+			Name: "expect-unchanged",
+			Usage: "Fail the deploy if the function is modified by someone else while it is running. " +
+				"The revision id is captured at the start and checked on every update.",
+			EnvVars: []string{"PLUGIN_EXPECT_UNCHANGED", "EXPECT_UNCHANGED", "INPUT_EXPECT_UNCHANGED"},
+		},
 		&cli.StringFlag{
 			Name: "s3-bucket",
 			Usage: "An Amazon S3 bucket in the same AWS Region as your function. " +
@@ -121,11 +94,6 @@ func main() {
 				"access permissions without modifying the function code.",
 			EnvVars: []string{"PLUGIN_DRY_RUN", "DRY_RUN", "INPUT_DRY_RUN"},
 		},
-		&cli.BoolFlag{
-			Name:    "debug",
-			Usage:   "Show debug message after upload the lambda successfully.",
-			EnvVars: []string{"PLUGIN_DEBUG", "DEBUG", "INPUT_DEBUG"},
-		},
 		&cli.BoolFlag{
 			Name:    "publish",
 			Usage:   "Set to true to publish a new version of the function after updating the code.",
@@ -164,6 +132,97 @@ func main() {
 			Usage:   "Lambda Environment variables",
 			EnvVars: []string{"PLUGIN_ENVIRONMENT", "ENVIRONMENT", "INPUT_ENVIRONMENT"},
 		},
+		&cli.StringSliceFlag{
+			Name: "environment-file",
+			Usage: "dotenv, JSON or YAML files with Lambda Environment variables, " +
+				"applied in order before the inline environment",
+			EnvVars: []string{"PLUGIN_ENVIRONMENT_FILE", "ENVIRONMENT_FILE", "INPUT_ENVIRONMENT_FILE"},
+		},
+		&cli.StringFlag{
+			Name: "environment-mode",
+			Usage: "How environment variables are applied: replace overwrites every variable, " +
+				"merge keeps the existing variables and overlays the new ones.",
+			Value:   EnvModeReplace,
+			EnvVars: []string{"PLUGIN_ENVIRONMENT_MODE", "ENVIRONMENT_MODE", "INPUT_ENVIRONMENT_MODE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "environment-remove",
+			Usage:   "Environment variables to remove from the function in merge mode",
+			EnvVars: []string{"PLUGIN_ENVIRONMENT_REMOVE", "ENVIRONMENT_REMOVE", "INPUT_ENVIRONMENT_REMOVE"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "secret-patterns",
+			Usage:   "Environment variable name patterns whose values are masked in debug output",
+			Value:   cli.NewStringSlice(defaultSecretPatterns...),
+			EnvVars: []string{"PLUGIN_SECRET_PATTERNS", "SECRET_PATTERNS", "INPUT_SECRET_PATTERNS"},
+		},
+		&cli.StringSliceFlag{
+			Name: "clear",
+			Usage: "Configuration fields to clear: description, layers, vpc, tracing, environment, " +
+				"reserved-concurrency. " +
+				"Unset settings leave the function unchanged.",
+			EnvVars: []string{"PLUGIN_CLEAR", "CLEAR", "INPUT_CLEAR"},
+		},
+		&cli.Int64Flag{
+			Name:    "ephemeral-storage",
+			Usage:   "The size of the /tmp directory in MB, between 512 and 10240",
+			EnvVars: []string{"PLUGIN_EPHEMERAL_STORAGE", "EPHEMERAL_STORAGE", "INPUT_EPHEMERAL_STORAGE"},
+		},
+		&cli.StringFlag{
+			Name:    "snap-start",
+			Usage:   "SnapStart setting: PublishedVersions or None",
+			EnvVars: []string{"PLUGIN_SNAP_START", "SNAP_START", "INPUT_SNAP_START"},
+		},
+		&cli.StringFlag{
+			Name:    "runtime-update-mode",
+			Usage:   "When the runtime is updated: Auto, FunctionUpdate or Manual",
+			EnvVars: []string{"PLUGIN_RUNTIME_UPDATE_MODE", "RUNTIME_UPDATE_MODE", "INPUT_RUNTIME_UPDATE_MODE"},
+		},
+		&cli.StringFlag{
+			Name:    "runtime-version-arn",
+			Usage:   "The runtime version to pin the function to, requires runtime update mode Manual",
+			EnvVars: []string{"PLUGIN_RUNTIME_VERSION_ARN", "RUNTIME_VERSION_ARN", "INPUT_RUNTIME_VERSION_ARN"},
+		},
+		&cli.StringFlag{
+			Name:    "function-log-format",
+			Usage:   "The log format of the function: Text or JSON",
+			EnvVars: []string{"PLUGIN_FUNCTION_LOG_FORMAT", "FUNCTION_LOG_FORMAT", "INPUT_FUNCTION_LOG_FORMAT"},
+		},
+		&cli.StringFlag{
+			Name:    "application-log-level",
+			Usage:   "The minimum level of application logs, requires function log format JSON",
+			EnvVars: []string{"PLUGIN_APPLICATION_LOG_LEVEL", "APPLICATION_LOG_LEVEL", "INPUT_APPLICATION_LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:    "system-log-level",
+			Usage:   "The minimum level of system logs, requires function log format JSON",
+			EnvVars: []string{"PLUGIN_SYSTEM_LOG_LEVEL", "SYSTEM_LOG_LEVEL", "INPUT_SYSTEM_LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:    "log-group",
+			Usage:   "The CloudWatch log group the function sends logs to",
+			EnvVars: []string{"PLUGIN_LOG_GROUP", "LOG_GROUP", "INPUT_LOG_GROUP"},
+		},
+		&cli.BoolFlag{
+			Name:    "create-log-group",
+			Usage:   "Create the log group of the function if it does not exist",
+			EnvVars: []string{"PLUGIN_CREATE_LOG_GROUP", "CREATE_LOG_GROUP", "INPUT_CREATE_LOG_GROUP"},
+		},
+		&cli.Int64Flag{
+			Name:    "log-retention-days",
+			Usage:   "The retention of the log group in days, requires create log group",
+			EnvVars: []string{"PLUGIN_LOG_RETENTION_DAYS", "LOG_RETENTION_DAYS", "INPUT_LOG_RETENTION_DAYS"},
+		},
+		&cli.Int64Flag{
+			Name:    "reserved-concurrency",
+			Usage:   "The reserved concurrency of the function, 0 stops every invocation",
+			EnvVars: []string{"PLUGIN_RESERVED_CONCURRENCY", "RESERVED_CONCURRENCY", "INPUT_RESERVED_CONCURRENCY"},
+		},
+		&cli.Int64Flag{
+			Name:    "provisioned-concurrency",
+			Usage:   "The provisioned concurrency of the published version, moved from older versions",
+			EnvVars: []string{"PLUGIN_PROVISIONED_CONCURRENCY", "PROVISIONED_CONCURRENCY", "INPUT_PROVISIONED_CONCURRENCY"},
+		},
 		&cli.StringSliceFlag{
 			Name:    "layers",
 			Usage:   "A list of function layers",
@@ -177,7 +236,98 @@ func main() {
 		&cli.StringFlag{
 			Name:    "commit.author",
 			Usage:   "git author name",
-			EnvVars: []string{"DRONE_COMMIT_AUTHOR"},
+			EnvVars: []string{"DRONE_COMMIT_AUTHOR", "GITHUB_ACTOR"},
+		},
+		&cli.StringFlag{
+			Name:    "commit.branch",
+			Usage:   "git commit branch",
+			EnvVars: []string{"DRONE_COMMIT_BRANCH", "GITHUB_REF_NAME"},
+		},
+		&cli.StringFlag{
+			Name:    "commit.tag",
+			Usage:   "git tag",
+			EnvVars: []string{"DRONE_TAG"},
+		},
+		&cli.StringFlag{
+			Name:    "build.number",
+			Usage:   "build number",
+			EnvVars: []string{"DRONE_BUILD_NUMBER", "GITHUB_RUN_NUMBER"},
+		},
+		&cli.StringFlag{
+			Name:    "result-file",
+			Usage:   "Write the deploy result (version, arn, code sha256, revision id) as JSON to this path",
+			EnvVars: []string{"PLUGIN_RESULT_FILE", "RESULT_FILE", "INPUT_RESULT_FILE"},
+		},
+		&cli.StringFlag{
+			Name:    "github-output",
+			Usage:   "GitHub Actions step output file",
+			EnvVars: []string{"GITHUB_OUTPUT"},
+		},
+		&cli.StringFlag{
+			Name:    "drone-output",
+			Usage:   "Drone step output file",
+			EnvVars: []string{"DRONE_OUTPUT"},
+		},
+		&cli.StringFlag{
+			Name:    "drone-card-path",
+			Usage:   "Drone card file for the deployment summary",
+			EnvVars: []string{"DRONE_CARD_PATH"},
+		},
+		&cli.StringFlag{
+			Name:    "github-step-summary",
+			Usage:   "GitHub Actions job summary file for the deployment summary",
+			EnvVars: []string{"GITHUB_STEP_SUMMARY"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "notify-webhooks",
+			Usage:   "Webhook URLs that receive a JSON notification for every deploy event",
+			EnvVars: []string{"PLUGIN_NOTIFY_WEBHOOKS", "NOTIFY_WEBHOOKS", "INPUT_NOTIFY_WEBHOOKS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "notify-slack",
+			Usage:   "Slack incoming webhook URLs that receive a message for every deploy event",
+			EnvVars: []string{"PLUGIN_NOTIFY_SLACK", "NOTIFY_SLACK", "INPUT_NOTIFY_SLACK"},
+		},
+		&cli.StringFlag{
+			Name:    "notify-template",
+			Usage:   "Go template of the notification message",
+			EnvVars: []string{"PLUGIN_NOTIFY_TEMPLATE", "NOTIFY_TEMPLATE", "INPUT_NOTIFY_TEMPLATE"},
+		},
+		&cli.StringSliceFlag{
+			Name: "notify-events",
+			Usage: "Deploy events to notify: started, config_updated, code_updated, published, failed. " +
+				"Defaults to all events.",
+			EnvVars: []string{"PLUGIN_NOTIFY_EVENTS", "NOTIFY_EVENTS", "INPUT_NOTIFY_EVENTS"},
+		},
+		&cli.StringFlag{
+			Name:    "otlp-endpoint",
+			Usage:   "OTLP/HTTP endpoint that receives a trace of the deploy, e.g. http://localhost:4318",
+			EnvVars: []string{"PLUGIN_OTLP_ENDPOINT", "OTLP_ENDPOINT", "INPUT_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "otlp-headers",
+			Usage:   "Headers sent to the OTLP endpoint, in KEY=value format",
+			EnvVars: []string{"PLUGIN_OTLP_HEADERS", "OTLP_HEADERS", "INPUT_OTLP_HEADERS"},
+		},
+		&cli.StringFlag{
+			Name:    "repo.name",
+			Usage:   "repository full name",
+			EnvVars: []string{"DRONE_REPO", "GITHUB_REPOSITORY"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "tags",
+			Usage:   "Tags to set on the function after every deploy, in KEY=value format",
+			EnvVars: []string{"PLUGIN_TAGS", "TAGS", "INPUT_TAGS"},
+		},
+		&cli.BoolFlag{
+			Name:    "tags-prune",
+			Usage:   "Remove deployment metadata tags that are no longer set",
+			EnvVars: []string{"PLUGIN_TAGS_PRUNE", "TAGS_PRUNE", "INPUT_TAGS_PRUNE"},
+		},
+		&cli.BoolFlag{
+			Name:    "skip-tags",
+			Usage:   "Do not tag the function with deployment metadata",
+			EnvVars: []string{"PLUGIN_SKIP_TAGS", "SKIP_TAGS", "INPUT_SKIP_TAGS"},
 		},
 		&cli.StringFlag{
 			Name:    "image-uri",
@@ -210,6 +360,12 @@ func main() {
 			EnvVars: []string{"PLUGIN_MAX_ATTEMPTS", "MAX_ATTEMPTS", "INPUT_MAX_ATTEMPTS"},
 			Value:   200,
 		},
+		&cli.IntFlag{
+			Name:    "max-retries",
+			Usage:   "the maximum number of times a conflicting or throttled update is retried",
+			EnvVars: []string{"PLUGIN_MAX_RETRIES", "MAX_RETRIES", "INPUT_MAX_RETRIES"},
+			Value:   5,
+		},
 		&cli.StringSliceFlag{
 			Name:    "architectures",
 			Usage:   "determines the type of computer processor that Lambda uses to run the function.",
@@ -220,14 +376,29 @@ func main() {
 			Usage:   "Enables or disables dual-stack IPv6 support in the VPC configuration for the Lambda function.",
 			EnvVars: []string{"PLUGIN_IPV6_DUAL_STACK", "IPV6_DUAL_STACK", "INPUT_IPV6_DUAL_STACK"},
 		},
-	}
+	}...)
 
 	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+		slog.Error("command failed", "error", err, "exit_code", exitCode(err))
+		os.Exit(exitCode(err))
 	}
 }
 
+// commitTag returns the git tag of the build. GitHub Actions has no tag
+// variable, so the ref name is used when the ref is a tag.
+func commitTag(tag string) string {
+	if tag == "" && os.Getenv("GITHUB_REF_TYPE") == "tag" {
+		return os.Getenv("GITHUB_REF_NAME")
+	}
+	return tag
+}
+
 func run(c *cli.Context) error {
+	logger, err := setupLogger(c)
+	if err != nil {
+		return err
+	}
+
 	plugin := Plugin{
 		Config: Config{
 			Region:          c.String("region"),
@@ -260,12 +431,70 @@ func run(c *cli.Context) error {
 			MaxAttempts:     c.Int("max-attempts"),
 			Architectures:   c.StringSlice("architectures"),
 			IP6DualStack:    c.Bool("ipv6-dual-stack"),
+			MaxRetries:      c.Int("max-retries"),
+			ExpectUnchanged: c.Bool("expect-unchanged"),
+
+			EnvironmentMode:   c.String("environment-mode"),
+			EnvironmentRemove: c.StringSlice("environment-remove"),
+			EnvironmentFile:   c.StringSlice("environment-file"),
+
+			Tags:      c.StringSlice("tags"),
+			TagsPrune: c.Bool("tags-prune"),
+			SkipTags:  c.Bool("skip-tags"),
+
+			SecretPatterns: c.StringSlice("secret-patterns"),
+			LogFormat:      c.String("log-format"),
+
+			ResultFile:   c.String("result-file"),
+			GitHubOutput: c.String("github-output"),
+			DroneOutput:  c.String("drone-output"),
+			CardPath:     c.String("drone-card-path"),
+			StepSummary:  c.String("github-step-summary"),
+
+			NotifyWebhooks: c.StringSlice("notify-webhooks"),
+			NotifySlack:    c.StringSlice("notify-slack"),
+			NotifyTemplate: c.String("notify-template"),
+			NotifyEvents:   c.StringSlice("notify-events"),
+
+			OTLPEndpoint: c.String("otlp-endpoint"),
+			OTLPHeaders:  c.StringSlice("otlp-headers"),
+
+			Clear: c.StringSlice("clear"),
+
+			EphemeralStorage:  c.Int64("ephemeral-storage"),
+			SnapStart:         c.String("snap-start"),
+			RuntimeUpdateMode: c.String("runtime-update-mode"),
+			RuntimeVersionArn: c.String("runtime-version-arn"),
+
+			FunctionLogFormat:   c.String("function-log-format"),
+			ApplicationLogLevel: c.String("application-log-level"),
+			SystemLogLevel:      c.String("system-log-level"),
+			LogGroup:            c.String("log-group"),
+			CreateLogGroup:      c.Bool("create-log-group"),
+			LogRetentionDays:    c.Int64("log-retention-days"),
+
+			ProvisionedConcurrency: c.Int64("provisioned-concurrency"),
+
+			RoleARN:              c.String("role-arn"),
+			RoleSessionName:      c.String("role-session-name"),
+			WebIdentityToken:     c.String("web-identity-token"),
+			WebIdentityTokenFile: c.String("web-identity-token-file"),
 		},
 		Commit: Commit{
-			Sha:    c.String("commit.sha"),
-			Author: c.String("commit.author"),
+			Sha:         c.String("commit.sha"),
+			Author:      c.String("commit.author"),
+			Branch:      c.String("commit.branch"),
+			Tag:         commitTag(c.String("commit.tag")),
+			BuildNumber: c.String("build.number"),
+			Repo:        c.String("repo.name"),
 		},
 	}
 
+	if c.IsSet("reserved-concurrency") {
+		plugin.Config.ReservedConcurrency = aws.Int64(c.Int64("reserved-concurrency"))
+	}
+
+	plugin.log = logger.With("version", Version)
+
 	return plugin.Exec(c.Context)
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Event is a deploy phase boundary that triggers notifications.
+type Event string
+
+// Deploy events.
+const (
+	EventStarted       Event = "started"
+	EventConfigUpdated Event = "config_updated"
+	EventCodeUpdated   Event = "code_updated"
+	EventPublished     Event = "published"
+	EventFailed        Event = "failed"
+)
+
+const defaultNotifyTemplate = `{{.Function}} {{.Event}}` +
+	`{{if .Version}} (version {{.Version}}){{end}}` +
+	`{{if .Commit}} at {{.Commit}}{{end}}` +
+	`{{if .Error}}: {{.Error}}{{end}}`
+
+// notifyAttempts is the number of times a notification is sent before
+// giving up.
+const notifyAttempts = 3
+
+// Notification is the JSON body posted to generic webhooks.
+type Notification struct {
+	Event    Event     `json:"event"`
+	Function string    `json:"function"`
+	Region   string    `json:"region"`
+	Version  string    `json:"version,omitempty"`
+	Commit   string    `json:"commit,omitempty"`
+	Author   string    `json:"author,omitempty"`
+	Repo     string    `json:"repo,omitempty"`
+	Build    string    `json:"build,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Message  string    `json:"message"`
+	Time     time.Time `json:"time"`
+}
+
+// notifier posts deploy events to generic webhooks and Slack.
+type notifier struct {
+	client   *http.Client
+	webhooks []string
+	slack    []string
+	events   []Event
+	message  *template.Template
+}
+
+func (p Plugin) newNotifier() (*notifier, error) {
+	webhooks := trimValues(p.Config.NotifyWebhooks)
+	slack := trimValues(p.Config.NotifySlack)
+	if len(webhooks) == 0 && len(slack) == 0 {
+		return nil, nil
+	}
+
+	text := p.Config.NotifyTemplate
+	if text == "" {
+		text = defaultNotifyTemplate
+	}
+	message, err := template.New("message").Parse(text)
+	if err != nil {
+		return nil, configError("notify template: %w", err)
+	}
+
+	var events []Event
+	for _, event := range trimValues(p.Config.NotifyEvents) {
+		switch e := Event(event); e {
+		case EventStarted, EventConfigUpdated, EventCodeUpdated, EventPublished, EventFailed:
+			events = append(events, e)
+		default:
+			return nil, configError("invalid notify event %q", event)
+		}
+	}
+
+	return &notifier{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		webhooks: webhooks,
+		slack:    slack,
+		events:   events,
+		message:  message,
+	}, nil
+}
+
+// post sends body to url, retrying network errors, throttling and
+// server errors.
+func (n *notifier) post(ctx context.Context, url string, body []byte) error {
+	var err error
+	for attempt := range notifyAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff(attempt - 1)):
+			}
+		}
+
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		var resp *http.Response
+		resp, err = n.client.Do(req)
+		if err != nil {
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		default:
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+	}
+
+	return err
+}
+
+// send renders the message and posts the notification to every target.
+func (n *notifier) send(ctx context.Context, notification Notification) error {
+	if len(n.events) > 0 && !slices.Contains(n.events, notification.Event) {
+		return nil
+	}
+
+	var message strings.Builder
+	if err := n.message.Execute(&message, notification); err != nil {
+		return err
+	}
+	notification.Message = message.String()
+
+	webhook, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	slack, err := json.Marshal(map[string]string{"text": notification.Message})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, url := range n.webhooks {
+		errs = append(errs, n.post(ctx, url, webhook))
+	}
+	for _, url := range n.slack {
+		errs = append(errs, n.post(ctx, url, slack))
+	}
+
+	return errors.Join(errs...)
+}
+
+// notify sends event to the configured notifiers. Failed notifications
+// are logged and never fail the deploy.
+func (p *Plugin) notify(ctx context.Context, event Event, deployErr error) {
+	if p.notifier == nil {
+		return
+	}
+
+	notification := Notification{
+		Event:    event,
+		Function: p.Config.FunctionName,
+		Region:   p.Config.Region,
+		Commit:   p.Commit.Sha,
+		Author:   p.Commit.Author,
+		Repo:     p.Commit.Repo,
+		Build:    p.Commit.BuildNumber,
+		Time:     time.Now().UTC(),
+	}
+	if p.result != nil {
+		notification.Version = p.result.Version
+	}
+	if deployErr != nil {
+		notification.Error = deployErr.Error()
+	}
+
+	if err := p.notifier.send(ctx, notification); err != nil {
+		p.logger().Warn("unable to send notification", "phase", "notify", "event", event, "error", err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// notifyStub records the JSON bodies posted to it. The first failures
+// requests are answered with a server error.
+type notifyStub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	bodies   []map[string]any
+}
+
+func newNotifyStub(t *testing.T, failures int) *notifyStub {
+	t.Helper()
+
+	stub := &notifyStub{failures: failures}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		if stub.failures > 0 {
+			stub.failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		stub.bodies = append(stub.bodies, body)
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+func (s *notifyStub) field(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var values []string
+	for _, body := range s.bodies {
+		value, _ := body[name].(string)
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestPlugin_notify_deploy(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = time.Second })
+
+	webhook := newNotifyStub(t, 1)
+	slack := newNotifyStub(t, 0)
+
+	p := &Plugin{
+		Config: Config{
+			FunctionName:   "api",
+			Region:         "eu-west-1",
+			SkipTags:       true,
+			NotifyWebhooks: []string{webhook.URL},
+			NotifySlack:    []string{slack.URL},
+		},
+		Commit: Commit{Sha: "e5f9a8b7", Author: "appleboy"},
+	}
+	n, err := p.newNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.notifier = n
+
+	cfg := &lambda.UpdateFunctionConfigurationInput{FunctionName: aws.String("api")}
+	cfg.SetMemorySize(256)
+	input := &lambda.UpdateFunctionCodeInput{FunctionName: aws.String("api")}
+	input.SetPublish(true)
+	if err := p.deploy(context.Background(), newFakeLambda(), cfg, input); err != nil {
+		t.Fatal(err)
+	}
+
+	wantEvents := []string{"config_updated", "code_updated", "published"}
+	if got := webhook.field("event"); !reflect.DeepEqual(got, wantEvents) {
+		t.Errorf("webhook events = %v, want %v", got, wantEvents)
+	}
+	if got := webhook.field("version"); got[len(got)-1] != "1" {
+		t.Errorf("webhook version = %v, want 1", got)
+	}
+
+	wantMessages := []string{
+		"api config_updated at e5f9a8b7",
+		"api code_updated (version 1) at e5f9a8b7",
+		"api published (version 1) at e5f9a8b7",
+	}
+	if got := slack.field("text"); !reflect.DeepEqual(got, wantMessages) {
+		t.Errorf("slack messages = %v, want %v", got, wantMessages)
+	}
+}
+
+func TestPlugin_Exec_notifyFailed(t *testing.T) {
+	webhook := newNotifyStub(t, 0)
+
+	p := Plugin{Config: Config{
+		FunctionName:   "api",
+		NotifyWebhooks: []string{webhook.URL},
+		NotifyTemplate: "{{.Function}} deploy {{.Event}}: {{.Error}}",
+	}}
+	err := p.Exec(context.Background())
+	if err == nil {
+		t.Fatal("Exec() expected error")
+	}
+
+	if got := webhook.field("event"); !reflect.DeepEqual(got, []string{"failed"}) {
+		t.Fatalf("webhook events = %v, want [failed]", got)
+	}
+	if got := webhook.field("message")[0]; !strings.HasPrefix(got, "api deploy failed: invalid configuration") {
+		t.Errorf("webhook message = %q", got)
+	}
+}
+
+func TestPlugin_notify_events(t *testing.T) {
+	webhook := newNotifyStub(t, 0)
+
+	p := &Plugin{Config: Config{
+		FunctionName:   "api",
+		NotifyWebhooks: []string{webhook.URL},
+		NotifyEvents:   []string{"published", "failed"},
+	}}
+	n, err := p.newNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.notifier = n
+
+	for _, event := range []Event{EventStarted, EventCodeUpdated, EventPublished} {
+		p.notify(context.Background(), event, nil)
+	}
+	if got := webhook.field("event"); !reflect.DeepEqual(got, []string{"published"}) {
+		t.Errorf("webhook events = %v, want [published]", got)
+	}
+
+	p.Config.NotifyEvents = []string{"rollback"}
+	if _, err := p.newNotifier(); err == nil {
+		t.Errorf("newNotifier() expected error for an invalid event")
+	}
+}
+
+func Test_notifier_post_gives_up(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = time.Second })
+
+	webhook := newNotifyStub(t, notifyAttempts)
+	n, err := Plugin{Config: Config{NotifyWebhooks: []string{webhook.URL}}}.newNotifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.send(context.Background(), Notification{Event: EventFailed}); err == nil {
+		t.Errorf("send() expected error after %d attempts", notifyAttempts)
+	}
+	if got := webhook.field("event"); len(got) != 0 {
+		t.Errorf("webhook received %v", got)
+	}
+}
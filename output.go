@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// Result describes the deployed function.
+type Result struct {
+	FunctionName string `json:"function_name"`
+	FunctionArn  string `json:"function_arn"`
+	Version      string `json:"version"`
+	CodeSha256   string `json:"code_sha256"`
+	CodeSize     int64  `json:"code_size"`
+	RevisionID   string `json:"revision_id"`
+	LastModified string `json:"last_modified"`
+	DryRun       bool   `json:"dry_run"`
+}
+
+func newResult(c *lambda.FunctionConfiguration, dryRun bool) *Result {
+	return &Result{
+		FunctionName: aws.StringValue(c.FunctionName),
+		FunctionArn:  aws.StringValue(c.FunctionArn),
+		Version:      aws.StringValue(c.Version),
+		CodeSha256:   aws.StringValue(c.CodeSha256),
+		CodeSize:     aws.Int64Value(c.CodeSize),
+		RevisionID:   aws.StringValue(c.RevisionId),
+		LastModified: aws.StringValue(c.LastModified),
+		DryRun:       dryRun,
+	}
+}
+
+// outputs returns the result as ordered step output pairs.
+func (r *Result) outputs() [][2]string {
+	return [][2]string{
+		{"function_name", r.FunctionName},
+		{"function_arn", r.FunctionArn},
+		{"version", r.Version},
+		{"code_sha256", r.CodeSha256},
+		{"code_size", strconv.FormatInt(r.CodeSize, 10)},
+		{"revision_id", r.RevisionID},
+		{"last_modified", r.LastModified},
+		{"dry_run", strconv.FormatBool(r.DryRun)},
+	}
+}
+
+// appendOutputs appends the outputs as name=value lines, the format
+// shared by $GITHUB_OUTPUT and Drone's dotenv $DRONE_OUTPUT.
+func appendOutputs(path string, outputs [][2]string) error {
+	var b strings.Builder
+	for _, output := range outputs {
+		if strings.ContainsAny(output[1], "\r\n") {
+			return fmt.Errorf("output %s contains a newline", output[0])
+		}
+		fmt.Fprintf(&b, "%s=%s\n", output[0], output[1])
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeOutputs writes the deploy result to the result file and to the
+// step outputs of the CI system.
+func (p *Plugin) writeOutputs() error {
+	if p.result == nil {
+		return nil
+	}
+
+	if p.Config.ResultFile != "" {
+		content, err := json.MarshalIndent(p.result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(p.Config.ResultFile, append(content, '\n'), 0o644); err != nil {
+			return fmt.Errorf("write result file: %w", err)
+		}
+	}
+
+	for _, path := range []string{p.Config.GitHubOutput, p.Config.DroneOutput} {
+		if path == "" {
+			continue
+		}
+		if err := appendOutputs(path, p.result.outputs()); err != nil {
+			return fmt.Errorf("write step outputs: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func TestPlugin_writeOutputs(t *testing.T) {
+	dir := t.TempDir()
+	githubOutput := filepath.Join(dir, "github_output")
+	if err := os.WriteFile(githubOutput, []byte("previous=step\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := newFakeLambda()
+	svc.config.Version = aws.String("7")
+	svc.config.LastModified = aws.String("2024-05-01T12:00:00.000+0000")
+
+	p := &Plugin{Config: Config{
+		FunctionName: "test",
+		SkipTags:     true,
+		ResultFile:   filepath.Join(dir, "result.json"),
+		GitHubOutput: githubOutput,
+		DroneOutput:  filepath.Join(dir, "drone_output"),
+	}}
+	if err := p.deploy(
+		context.Background(),
+		svc,
+		nil,
+		&lambda.UpdateFunctionCodeInput{FunctionName: aws.String("test")},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.writeOutputs(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(p.Config.ResultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result Result
+	if err := json.Unmarshal(content, &result); err != nil {
+		t.Fatal(err)
+	}
+	want := Result{
+		FunctionName: "test",
+		FunctionArn:  "arn:aws:lambda:us-east-1:123456789012:function:test",
+		Version:      "7",
+		CodeSha256:   "sha-1",
+		RevisionID:   "2",
+		LastModified: "2024-05-01T12:00:00.000+0000",
+	}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	outputs := "function_name=test\n" +
+		"function_arn=arn:aws:lambda:us-east-1:123456789012:function:test\n" +
+		"version=7\n" +
+		"code_sha256=sha-1\n" +
+		"code_size=0\n" +
+		"revision_id=2\n" +
+		"last_modified=2024-05-01T12:00:00.000+0000\n" +
+		"dry_run=false\n"
+	for path, want := range map[string]string{
+		githubOutput:         "previous=step\n" + outputs,
+		p.Config.DroneOutput: outputs,
+	} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(path), got, want)
+		}
+	}
+}
+
+func TestPlugin_writeResults_failure(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, LogFormatText, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	p := &Plugin{
+		Config: Config{
+			FunctionName: "test",
+			ResultFile:   filepath.Join(dir, "missing", "result.json"),
+			StepSummary:  filepath.Join(dir, "summary.md"),
+		},
+		result: &Result{FunctionName: "test", Version: "7"},
+		log:    logger,
+	}
+	p.writeResults(context.Background(), newFakeLambda())
+
+	if !strings.Contains(buf.String(), "outputs not written, the deploy succeeded") {
+		t.Errorf("missing warning in log:\n%s", buf.String())
+	}
+	// the summary is still written
+	if _, err := os.Stat(p.Config.StepSummary); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_appendOutputs_newline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	err := appendOutputs(path, [][2]string{{"key", "multi\nline"}})
+	if err == nil || !strings.Contains(err.Error(), "newline") {
+		t.Errorf("appendOutputs() error = %v, want newline error", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Manifest describes a deployment package.
+type Manifest struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// SHA256 is the hex encoded digest, like sha256sum prints it.
+	SHA256 string `json:"sha256"`
+	// CodeSha256 is the base64 encoded digest, like Lambda reports it.
+	CodeSha256 string         `json:"code_sha256"`
+	Files      []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file in the deployment package.
+type ManifestFile struct {
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+}
+
+func packageCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "package",
+		Usage: "Build the deployment zip without deploying it",
+		Description: "The zip can be deployed by later steps with zip-file. " +
+			"No AWS credentials or function name are needed.",
+		Flags: append([]cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "source",
+				Usage:   "zip file list",
+				EnvVars: []string{"PLUGIN_SOURCE", "SOURCE", "INPUT_SOURCE"},
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Path of the deployment zip",
+				Value:   "lambda.zip",
+				EnvVars: []string{"PLUGIN_OUTPUT", "OUTPUT", "INPUT_OUTPUT"},
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Usage:   "Also write the manifest as JSON to this path",
+				EnvVars: []string{"PLUGIN_MANIFEST", "MANIFEST", "INPUT_MANIFEST"},
+			},
+		}, logFlags()...),
+		Action: func(c *cli.Context) error {
+			if _, err := setupLogger(c); err != nil {
+				return err
+			}
+
+			m, err := packageSources(c.StringSlice("source"), c.String("output"))
+			if err != nil {
+				return err
+			}
+
+			return m.write(os.Stdout, c.String("manifest"))
+		},
+	}
+}
+
+// packageSources builds the deployment zip from the source globs at dest
+// and returns its manifest.
+func packageSources(sources []string, dest string) (*Manifest, error) {
+	sources = trimValues(sources)
+	if len(sources) == 0 {
+		return nil, configError("missing source files")
+	}
+
+	files := globList(sources)
+	if len(files) == 0 {
+		return nil, configError("no files match the sources %v", sources)
+	}
+
+	if dir := filepath.Dir(dest); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create output directory: %w", err)
+		}
+	}
+	if err := createZip(files, dest); err != nil {
+		return nil, wrapError("create zip", err)
+	}
+
+	return newManifest(dest)
+}
+
+// newManifest lists and hashes the zip at path.
+func newManifest(path string) (*Manifest, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("read zip: %w", err)
+	}
+	defer r.Close()
+
+	m := &Manifest{Path: path, Files: make([]ManifestFile, 0, len(r.File))}
+	for _, f := range r.File {
+		m.Files = append(m.Files, ManifestFile{Name: f.Name, Size: f.UncompressedSize64})
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if m.Size, err = io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	sum := h.Sum(nil)
+	m.SHA256 = hex.EncodeToString(sum)
+	m.CodeSha256 = base64.StdEncoding.EncodeToString(sum)
+
+	return m, nil
+}
+
+// write prints the manifest to w and, if path is set, writes it to path.
+func (m *Manifest) write(w io.Writer, path string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+
+	if path != "" {
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return fmt.Errorf("write manifest: %w", err)
+		}
+	}
+
+	_, err = w.Write(content)
+	return err
+}
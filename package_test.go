@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree creates the files below dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_packageSources_outputInSource(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"main": "binary"})
+	dest := filepath.Join(dir, "lambda.zip")
+
+	// the second run globs the zip of the first one
+	for _, source := range []string{dir, filepath.Join(dir, "*")} {
+		m, err := packageSources([]string{source}, dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := "main"
+		if source == dir {
+			name = filepath.Base(dir) + "/main"
+		}
+		if want := []ManifestFile{{Name: name, Size: 6}}; !reflect.DeepEqual(m.Files, want) {
+			t.Errorf("source %s: files = %v, want %v", source, m.Files, want)
+		}
+	}
+}
+
+func Test_packageSources(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"src/main":           "binary",
+		"src/config/app.yml": "port: 8080",
+		"README.md":          "readme",
+	})
+
+	dest := filepath.Join(dir, "dist", "lambda.zip")
+	m, err := packageSources([]string{filepath.Join(dir, "src"), " " + filepath.Join(dir, "*.md")}, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := []ManifestFile{
+		{Name: "src/config/app.yml", Size: 10},
+		{Name: "src/main", Size: 6},
+		{Name: "README.md", Size: 6},
+	}
+	if !reflect.DeepEqual(m.Files, wantFiles) {
+		t.Errorf("files = %v, want %v", m.Files, wantFiles)
+	}
+
+	content, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	if m.Size != int64(len(content)) {
+		t.Errorf("size = %d, want %d", m.Size, len(content))
+	}
+	if m.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("sha256 = %s", m.SHA256)
+	}
+	if m.CodeSha256 != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Errorf("code sha256 = %s", m.CodeSha256)
+	}
+
+	var out bytes.Buffer
+	path := filepath.Join(dir, "manifest.json")
+	if err := m.write(&out, path); err != nil {
+		t.Fatal(err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, out.Bytes()) {
+		t.Error("manifest file differs from the printed manifest")
+	}
+	var decoded Manifest
+	if err := json.Unmarshal(written, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, m) {
+		t.Errorf("manifest = %+v, want %+v", decoded, m)
+	}
+}
+
+func Test_packageSources_errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		sources []string
+	}{
+		{name: "no sources", sources: []string{" "}},
+		{name: "no matches", sources: []string{filepath.Join(dir, "missing", "*")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := packageSources(tt.sources, filepath.Join(dir, "lambda.zip"))
+			if exitCode(err) != int(KindInvalidConfig) {
+				t.Errorf("packageSources() error = %v, want invalid config", err)
+			}
+		})
+	}
+}
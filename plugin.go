@@ -5,18 +5,20 @@ import (
 	"context"
 	"errors"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
 	"github.com/gookit/goutil/dump"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -52,18 +54,89 @@ type (
 		MaxAttempts     int
 		Architectures   []string
 		IP6DualStack    bool
+		MaxRetries      int
+		ExpectUnchanged bool
+
+		EnvironmentMode   string
+		EnvironmentRemove []string
+		EnvironmentFile   []string
+
+		Tags      []string
+		TagsPrune bool
+		SkipTags  bool
+
+		SecretPatterns []string
+		LogFormat      string
+
+		ResultFile   string
+		GitHubOutput string
+		DroneOutput  string
+		CardPath     string
+		StepSummary  string
+
+		NotifyWebhooks []string
+		NotifySlack    []string
+		NotifyTemplate string
+		NotifyEvents   []string
+
+		OTLPEndpoint string
+		OTLPHeaders  []string
+
+		Clear []string
+
+		EphemeralStorage  int64
+		SnapStart         string
+		RuntimeUpdateMode string
+		RuntimeVersionArn string
+
+		FunctionLogFormat   string
+		ApplicationLogLevel string
+		SystemLogLevel      string
+		LogGroup            string
+		CreateLogGroup      bool
+		LogRetentionDays    int64
+
+		// ReservedConcurrency is nil if unset, as zero stops every invocation.
+		ReservedConcurrency    *int64
+		ProvisionedConcurrency int64
+
+		RoleARN              string
+		RoleSessionName      string
+		WebIdentityToken     string
+		WebIdentityTokenFile string
 	}
 
 	// Commit information.
 	Commit struct {
-		Sha    string
-		Author string
+		Sha         string
+		Author      string
+		Branch      string
+		Tag         string
+		BuildNumber string
+		Repo        string
 	}
 
 	// Plugin values.
 	Plugin struct {
 		Config Config
 		Commit Commit
+
+		// secretKeys are the environment variables resolved from secret
+		// references, whose values must never be logged.
+		secretKeys map[string]bool
+
+		log    *slog.Logger
+		result *Result
+
+		// previous and configured are the function configuration before
+		// the deploy and after the configuration update, waited is the
+		// time spent waiting for the function to be ready.
+		previous   *lambda.FunctionConfiguration
+		configured *lambda.FunctionConfiguration
+		waited     time.Duration
+
+		notifier *notifier
+		tracer   trace.Tracer
 	}
 )
 
@@ -79,18 +152,60 @@ func getEnvironment(envs []string) map[string]string {
 	return output
 }
 
-func (p Plugin) loadEnvironment(envs []string) *lambda.Environment {
-	return &lambda.Environment{
-		Variables: aws.StringMap(getEnvironment(envs)),
+// Exec executes the plugin.
+func (p Plugin) Exec(ctx context.Context) (err error) {
+	n, err := p.newNotifier()
+	if err != nil {
+		return err
+	}
+	p.notifier = n
+
+	tp, err := p.newTracerProvider(ctx)
+	if err != nil {
+		return err
+	}
+	if tp != nil {
+		p.tracer = tp.Tracer(tracerName)
+		defer func() {
+			if err := shutdownTracer(ctx, tp); err != nil {
+				p.logger().Warn("unable to export traces", "phase", "trace", "error", err)
+			}
+		}()
+	}
+
+	ctx, span := p.startSpan(ctx, "deploy")
+	defer func() { endSpan(span, err) }()
+
+	if err := p.exec(ctx); err != nil {
+		// the failure is sent without cancellation, so that it is
+		// delivered even when the deploy was canceled
+		p.notify(context.WithoutCancel(ctx), EventFailed, err)
+		return err
 	}
+
+	return nil
 }
 
-// Exec executes the plugin.
-func (p Plugin) Exec(ctx context.Context) error { //nolint:gocyclo
+func (p *Plugin) exec(ctx context.Context) error { //nolint:gocyclo
 	p.dump(p.Config)
 
+	if err := p.expandConfig(); err != nil {
+		return err
+	}
+
 	if p.Config.FunctionName == "" {
-		return errors.New("missing lambda function name")
+		return configError("missing lambda function name")
+	}
+	trace.SpanFromContext(ctx).SetAttributes(p.traceAttributes()...)
+
+	if err := p.validateEnvironmentMode(); err != nil {
+		return err
+	}
+	if err := p.validateClear(); err != nil {
+		return err
+	}
+	if err := p.validateConfiguration(); err != nil {
+		return err
 	}
 
 	sources := trimValues(p.Config.Source)
@@ -99,28 +214,15 @@ func (p Plugin) Exec(ctx context.Context) error { //nolint:gocyclo
 		len(sources) == 0 &&
 		p.Config.ZipFile == "" &&
 		p.Config.ImageURI == "" {
-		return errors.New("missing zip source or s3 bucket/key or image uri")
-	}
-
-	// Create Lambda service client
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-	}))
-
-	config := &aws.Config{
-		Region: aws.String(p.Config.Region),
+		return configError("missing zip source or s3 bucket/key or image uri")
 	}
 
-	if p.Config.Profile != "" {
-		config.Credentials = credentials.NewSharedCredentials("", p.Config.Profile)
-	}
+	p.notify(ctx, EventStarted, nil)
 
-	if p.Config.AccessKey != "" && p.Config.SecretKey != "" {
-		config.Credentials = credentials.NewStaticCredentials(
-			p.Config.AccessKey,
-			p.Config.SecretKey,
-			p.Config.SessionToken,
-		)
+	// Create Lambda service client
+	sess, err := p.newSession()
+	if err != nil {
+		return wrapError("create session", err)
 	}
 
 	if p.Config.DryRun {
@@ -138,10 +240,6 @@ func (p Plugin) Exec(ctx context.Context) error { //nolint:gocyclo
 		input.SetImageUri(p.Config.ImageURI)
 	}
 
-	if p.Config.ReversionID != "" {
-		input.SetRevisionId(p.Config.ReversionID)
-	}
-
 	if p.Config.S3Bucket != "" && p.Config.S3Key != "" {
 		input.SetS3Key(p.Config.S3Key)
 		input.SetS3Bucket(p.Config.S3Bucket)
@@ -157,11 +255,23 @@ func (p Plugin) Exec(ctx context.Context) error { //nolint:gocyclo
 	}
 
 	if len(sources) != 0 {
+		_, span := p.startSpan(ctx, "glob_list")
 		files := globList(sources)
+		span.SetAttributes(attribute.Int("lambda.package.files", len(files)))
+		endSpan(span, nil)
+
 		path := os.TempDir() + "/output.zip"
 		if len(files) != 0 {
-			if err := createZip(files, path); err != nil {
-				return err
+			zipCtx, done := p.phase(ctx, "package", "create zip",
+				attribute.Int("lambda.package.files", len(files)),
+			)
+			err := createZip(files, path)
+			if info, statErr := os.Stat(path); err == nil && statErr == nil {
+				trace.SpanFromContext(zipCtx).SetAttributes(attribute.Int64("lambda.package.size", info.Size()))
+			}
+			done(err)
+			if err != nil {
+				return wrapError("create zip", err)
 			}
 
 			p.Config.ZipFile = path
@@ -171,190 +281,249 @@ func (p Plugin) Exec(ctx context.Context) error { //nolint:gocyclo
 	if p.Config.ZipFile != "" {
 		contents, err := os.ReadFile(p.Config.ZipFile)
 		if err != nil {
-			return err
+			return configError("read zip file: %w", err)
 		}
 
 		input.SetZipFile(contents)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int("lambda.package.size", len(contents)))
 	}
 
-	isUpdateConfig := false
-	cfg := &lambda.UpdateFunctionConfigurationInput{}
-	cfg.SetFunctionName(p.Config.FunctionName)
-	if p.Config.MemorySize > 0 {
-		isUpdateConfig = true
-		cfg.SetMemorySize(p.Config.MemorySize)
-	}
-	if p.Config.Timeout > 0 {
-		isUpdateConfig = true
-		cfg.SetTimeout(p.Config.Timeout)
-	}
-	if len(p.Config.Handler) > 0 {
-		isUpdateConfig = true
-		cfg.SetHandler(p.Config.Handler)
+	variables, err := p.environmentVariables()
+	if err != nil {
+		return err
 	}
-	if len(p.Config.Role) > 0 {
-		isUpdateConfig = true
-		cfg.SetRole(p.Config.Role)
+	if err := p.expandEnvironment(variables); err != nil {
+		return err
 	}
-	if len(p.Config.Runtime) > 0 {
-		isUpdateConfig = true
-		cfg.SetRuntime(p.Config.Runtime)
+	cfg := p.configurationInput(variables)
+	if cfg != nil && cfg.Environment != nil {
+		if err := p.resolveSecrets(ctx, newSecretResolver(sess), cfg.Environment.Variables); err != nil {
+			return err
+		}
 	}
-	if p.Config.Description != "" {
-		isUpdateConfig = true
-		cfg.SetDescription(p.Config.Description)
+
+	// the log group has to exist before the function logs to it
+	if err := p.ensureLogGroup(ctx, cloudwatchlogs.New(sess)); err != nil {
+		return err
 	}
-	if len(p.Config.Layers) > 0 {
-		isUpdateConfig = true
-		cfg.SetLayers(aws.StringSlice(p.Config.Layers))
+
+	svc := lambda.New(sess)
+	if err := p.deploy(ctx, svc, cfg, input); err != nil {
+		return err
 	}
 
-	envs := trimValues(p.Config.Environment)
-	if len(envs) > 0 {
-		isUpdateConfig = true
-		cfg.SetEnvironment(p.loadEnvironment(envs))
+	p.writeResults(ctx, svc)
+
+	return nil
+}
+
+// writeResults writes the result file, the step outputs and the summary.
+// The code is already deployed, so a failure is only logged as a warning.
+func (p *Plugin) writeResults(ctx context.Context, svc lambdaiface.LambdaAPI) {
+	ctx, done := p.phase(ctx, "output", "write outputs")
+	err := errors.Join(p.writeOutputs(), p.writeSummary(ctx, svc))
+	done(err)
+	if err != nil {
+		p.logger().Warn("outputs not written, the deploy succeeded", "phase", "output", "error", err)
 	}
+}
 
-	subnets := trimValues(p.Config.Subnets)
-	securityGroups := trimValues(p.Config.SecurityGroups)
-	if len(subnets) > 0 || len(securityGroups) > 0 {
-		isUpdateConfig = true
-		cfg.SetVpcConfig(&lambda.VpcConfig{
-			Ipv6AllowedForDualStack: aws.Bool(p.Config.IP6DualStack),
-			SubnetIds:               aws.StringSlice(subnets),
-			SecurityGroupIds:        aws.StringSlice(securityGroups),
-		})
+// deploy applies the configuration update, if any, and then the code
+// update. When a revision id is in play, each returned RevisionId is
+// threaded into the next call so that concurrent changes are detected.
+func (p *Plugin) deploy(
+	ctx context.Context,
+	svc lambdaiface.LambdaAPI,
+	cfg *lambda.UpdateFunctionConfigurationInput,
+	input *lambda.UpdateFunctionCodeInput,
+) error {
+	revisionID := p.Config.ReversionID
+	captureRevision := p.Config.ExpectUnchanged && revisionID == ""
+	hasEnvironment := cfg != nil && cfg.Environment != nil
+
+	// the runtime management config cannot be checked against a revision
+	// id, so it runs before the revision is captured and threaded
+	revisionID, err := p.putRuntimeManagement(ctx, svc, revisionID)
+	if err != nil {
+		return err
 	}
 
-	if p.Config.TracingMode != "" {
-		isUpdateConfig = true
-		cfg.SetTracingConfig(&lambda.TracingConfig{
-			Mode: aws.String(p.Config.TracingMode),
+	var current *lambda.FunctionConfiguration
+	if captureRevision || hasEnvironment || p.summaryEnabled() {
+		current, err = svc.GetFunctionConfigurationWithContext(ctx, &lambda.GetFunctionConfigurationInput{
+			FunctionName: aws.String(p.Config.FunctionName),
 		})
+		if err != nil {
+			return wrapError("get function configuration", err)
+		}
+		p.previous = current
 	}
 
-	svc := lambda.New(sess, config)
+	if captureRevision {
+		revisionID = aws.StringValue(current.RevisionId)
+		p.logger().Info("captured revision id", "phase", "revision", "revision_id", revisionID)
+	}
 
-	if isUpdateConfig {
+	if cfg != nil {
 		// UpdateFunctionConfiguration API operation for AWS Lambda.
-		log.Println("Update function configuration ...")
-		if err := p.checkStatus(svc); err != nil {
-			return err
+		ctx, done := p.phase(ctx, "config", "update function configuration")
+		if hasEnvironment {
+			p.applyEnvironment(current, cfg.Environment)
 		}
-		lambdaConfig, err := svc.UpdateFunctionConfigurationWithContext(ctx, cfg)
-		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				case lambda.ErrCodeServiceException:
-					log.Println(lambda.ErrCodeServiceException, aerr.Error())
-				case lambda.ErrCodeResourceNotFoundException:
-					log.Println(lambda.ErrCodeResourceNotFoundException, aerr.Error())
-				case lambda.ErrCodeInvalidParameterValueException:
-					log.Println(lambda.ErrCodeInvalidParameterValueException, aerr.Error())
-				case lambda.ErrCodeTooManyRequestsException:
-					log.Println(lambda.ErrCodeTooManyRequestsException, aerr.Error())
-				case lambda.ErrCodeResourceConflictException:
-					log.Println(lambda.ErrCodeResourceConflictException, aerr.Error())
-				case lambda.ErrCodePreconditionFailedException:
-					log.Println(lambda.ErrCodePreconditionFailedException, aerr.Error())
-				default:
-					log.Println(aerr.Error())
-				}
-			} else {
-				// Print the error, cast err to awserr.Error to get the Code and
-				// Message from an error.
-				log.Println(err.Error())
-			}
+		if revisionID != "" {
+			cfg.SetRevisionId(revisionID)
+		}
+		var lambdaConfig *lambda.FunctionConfiguration
+		err := p.retry(ctx, svc, "update function configuration", func() (err error) {
+			lambdaConfig, err = svc.UpdateFunctionConfigurationWithContext(ctx, cfg)
 			return err
+		})
+		done(err)
+		if err != nil {
+			return revisionConflict(err, revisionID)
 		}
 
 		p.dump(lambdaConfig)
+		p.configured = lambdaConfig
+		p.notify(ctx, EventConfigUpdated, nil)
+		if revisionID != "" {
+			revisionID = aws.StringValue(lambdaConfig.RevisionId)
+		}
 	}
 
-	log.Println("Update function code ...")
-	if err := p.checkStatus(svc); err != nil {
+	codeCtx, done := p.phase(ctx, "code", "update function code",
+		attribute.Int("lambda.package.size", len(input.ZipFile)),
+		attribute.Bool("lambda.publish", aws.BoolValue(input.Publish)),
+	)
+	if revisionID != "" {
+		input.SetRevisionId(revisionID)
+	}
+	var lambdaConfig *lambda.FunctionConfiguration
+	err = p.retry(codeCtx, svc, "update function code", func() (err error) {
+		lambdaConfig, err = svc.UpdateFunctionCodeWithContext(codeCtx, input)
 		return err
+	})
+	if err == nil {
+		span := trace.SpanFromContext(codeCtx)
+		span.SetAttributes(
+			attribute.String("lambda.version", aws.StringValue(lambdaConfig.Version)),
+			attribute.String("lambda.code_sha256", aws.StringValue(lambdaConfig.CodeSha256)),
+		)
+		// the version is published by UpdateFunctionCode itself, so it is
+		// recorded as an event of the span
+		if aws.BoolValue(input.Publish) && !p.Config.DryRun {
+			span.AddEvent("version published")
+		}
 	}
-	lambdaConfig, err := svc.UpdateFunctionCodeWithContext(ctx, input)
+	done(err)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case lambda.ErrCodeServiceException:
-				log.Println(lambda.ErrCodeServiceException, aerr.Error())
-			case lambda.ErrCodeResourceNotFoundException:
-				log.Println(lambda.ErrCodeResourceNotFoundException, aerr.Error())
-			case lambda.ErrCodeInvalidParameterValueException:
-				log.Println(lambda.ErrCodeInvalidParameterValueException, aerr.Error())
-			case lambda.ErrCodeTooManyRequestsException:
-				log.Println(lambda.ErrCodeTooManyRequestsException, aerr.Error())
-			case lambda.ErrCodeCodeStorageExceededException:
-				log.Println(lambda.ErrCodeCodeStorageExceededException, aerr.Error())
-			case lambda.ErrCodeResourceConflictException:
-				log.Println(lambda.ErrCodeResourceConflictException, aerr.Error())
-			case lambda.ErrCodeResourceNotReadyException:
-				log.Println(lambda.ErrCodeResourceNotReadyException, aerr.Error())
-			default:
-				log.Println(aerr.Error())
+		return revisionConflict(err, revisionID)
+	}
+
+	p.dump(lambdaConfig)
+	p.result = newResult(lambdaConfig, p.Config.DryRun)
+	p.logger().Info("function code updated",
+		"phase", "code",
+		"function_version", aws.StringValue(lambdaConfig.Version),
+		"code_sha256", aws.StringValue(lambdaConfig.CodeSha256),
+		"revision_id", aws.StringValue(lambdaConfig.RevisionId),
+	)
+	p.notify(ctx, EventCodeUpdated, nil)
+	if aws.BoolValue(input.Publish) && !p.Config.DryRun {
+		if snapStartEnabled(lambdaConfig) {
+			if err := p.waitSnapStart(ctx, svc, aws.StringValue(lambdaConfig.Version)); err != nil {
+				return err
 			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			log.Println(err.Error())
 		}
+		p.notify(ctx, EventPublished, nil)
+	}
+
+	if err := p.putReservedConcurrency(ctx, svc); err != nil {
 		return err
 	}
+	if p.Config.ProvisionedConcurrency > 0 && aws.BoolValue(input.Publish) && !p.Config.DryRun {
+		if err := p.moveProvisionedConcurrency(ctx, svc, aws.StringValue(lambdaConfig.Version)); err != nil {
+			return err
+		}
+	}
 
-	p.dump(lambdaConfig)
+	if p.Config.DryRun || p.Config.SkipTags {
+		return nil
+	}
+
+	// the code is already deployed, so a missing tag permission must not
+	// fail the deploy
+	ctx, done = p.phase(ctx, "tags", "tag function")
+	err = p.tagFunction(ctx, svc, aws.StringValue(lambdaConfig.FunctionArn))
+	done(err)
+	if err != nil {
+		p.logger().Warn("function not tagged, the deploy succeeded", "phase", "tags", "error", err)
+	}
 
 	return nil
 }
 
-func (p *Plugin) checkStatus(svc *lambda.Lambda) error {
+func (p *Plugin) checkStatus(ctx context.Context, svc lambdaiface.LambdaAPI) (err error) {
 	// Check Lambda function states
 	// see https://docs.aws.amazon.com/lambda/latest/dg/functions-states.html
-	lambdaConfig, err := svc.GetFunctionConfiguration(&lambda.GetFunctionConfigurationInput{
+	ctx, span := p.startSpan(ctx, "check_status")
+	defer func() { endSpan(span, err) }()
+
+	waited := p.waited
+	defer func() { span.SetAttributes(attribute.Int64("lambda.waited_ms", (p.waited - waited).Milliseconds())) }()
+
+	lambdaConfig, err := svc.GetFunctionConfigurationWithContext(ctx, &lambda.GetFunctionConfigurationInput{
 		FunctionName: aws.String(p.Config.FunctionName),
 	})
 	if err != nil {
-		return err
-	}
-	log.Println("Current State:", aws.StringValue(lambdaConfig.State))
+		return wrapError("get function configuration", err)
+	}
+	span.SetAttributes(
+		attribute.String("lambda.state", aws.StringValue(lambdaConfig.State)),
+		attribute.String("lambda.last_update_status", aws.StringValue(lambdaConfig.LastUpdateStatus)),
+	)
+	logger := p.logger().With("phase", "status")
+	logger.Info("current state",
+		"state", aws.StringValue(lambdaConfig.State),
+		"last_update_status", aws.StringValue(lambdaConfig.LastUpdateStatus),
+	)
 	if aws.StringValue(lambdaConfig.State) != lambda.StateActive {
-		log.Println("Current State Reason:", aws.StringValue(lambdaConfig.StateReason))
-		log.Println("Current State Reason Code:", aws.StringValue(lambdaConfig.StateReasonCode))
-		log.Println("Waiting for Lambda function states to be active...")
-		if err := svc.WaitUntilFunctionActiveV2WithContext(
-			aws.BackgroundContext(),
+		logger.Info("waiting for function state to be active",
+			"state_reason", aws.StringValue(lambdaConfig.StateReason),
+			"state_reason_code", aws.StringValue(lambdaConfig.StateReasonCode),
+		)
+		start := time.Now()
+		err := svc.WaitUntilFunctionActiveV2WithContext(
+			ctx,
 			&lambda.GetFunctionInput{
 				FunctionName: aws.String(p.Config.FunctionName),
 			},
 			request.WithWaiterMaxAttempts(p.Config.MaxAttempts),
-		); err != nil {
-			log.Println(err.Error())
-			return err
+			p.waiterProgress("waiting for function state to be active"),
+		)
+		p.waited += time.Since(start)
+		if err != nil {
+			return wrapError("wait for function", err)
 		}
 	}
 
-	log.Println("Last Update Status:", aws.StringValue(lambdaConfig.LastUpdateStatus))
 	if aws.StringValue(lambdaConfig.LastUpdateStatus) != lambda.LastUpdateStatusSuccessful {
-		log.Println(
-			"Last Update Status Reason:",
-			aws.StringValue(lambdaConfig.LastUpdateStatusReason),
-		)
-		log.Println(
-			"Last Update Status ReasonCode:",
-			aws.StringValue(lambdaConfig.LastUpdateStatusReasonCode),
+		logger.Info("waiting for last update status to be successful",
+			"last_update_status_reason", aws.StringValue(lambdaConfig.LastUpdateStatusReason),
+			"last_update_status_reason_code", aws.StringValue(lambdaConfig.LastUpdateStatusReasonCode),
 		)
-		log.Println("Waiting for Last Update Status to be successful ...")
-		if err := svc.WaitUntilFunctionUpdatedV2WithContext(
-			aws.BackgroundContext(),
+		start := time.Now()
+		err := svc.WaitUntilFunctionUpdatedV2WithContext(
+			ctx,
 			&lambda.GetFunctionInput{
 				FunctionName: aws.String(p.Config.FunctionName),
 			},
 			request.WithWaiterMaxAttempts(p.Config.MaxAttempts),
-		); err != nil {
-			log.Println(err.Error())
-			return err
+			p.waiterProgress("waiting for last update status to be successful"),
+		)
+		p.waited += time.Since(start)
+		if err != nil {
+			return wrapError("wait for function", err)
 		}
 	}
 
@@ -366,6 +535,15 @@ func (p *Plugin) dump(val ...any) {
 		return
 	}
 
+	for i := range val {
+		val[i] = p.redact(val[i])
+	}
+
+	if p.Config.LogFormat == LogFormatJSON {
+		p.logger().Debug("dump", "value", val)
+		return
+	}
+
 	dump.P(val)
 }
 
@@ -391,7 +569,7 @@ func globList(paths []string) []string {
 		pattern = strings.Trim(pattern, " ")
 		matches, err := filepath.Glob(pattern)
 		if err != nil {
-			log.Printf("Glob error for %q: %s\n", pattern, err)
+			slog.Warn("invalid glob pattern", "pattern", pattern, "error", err)
 			continue
 		}
 
@@ -408,24 +586,35 @@ func createZip(files []string, dest string) error {
 	}
 	defer out.Close()
 
+	// the output may lie below a source, so it is skipped in the walk
+	self, err := out.Stat()
+	if err != nil {
+		return err
+	}
+
 	w := zip.NewWriter(out)
 	defer w.Close()
 
 	for _, src := range files {
-		if err := addToZip(w, src); err != nil {
+		if err := addToZip(w, src, self); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	// the archive is read right after, so errors flushing it must not be lost
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return out.Close()
 }
 
-func addToZip(w *zip.Writer, src string) error {
+func addToZip(w *zip.Writer, src string, skip os.FileInfo) error {
 	base := filepath.Dir(src)
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if info.IsDir() || os.SameFile(info, skip) {
 			return nil
 		}
 
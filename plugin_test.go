@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"maps"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
 )
 
 func Test_getEnvironment(t *testing.T) {
@@ -43,3 +54,231 @@ func Test_getEnvironment(t *testing.T) {
 		})
 	}
 }
+
+// fakeLambda is an in-memory stand-in for the Lambda API. Methods that
+// are not overridden panic through the embedded nil interface.
+type fakeLambda struct {
+	lambdaiface.LambdaAPI
+
+	config *lambda.FunctionConfiguration
+	calls  []string
+
+	configInput *lambda.UpdateFunctionConfigurationInput
+	codeInput   *lambda.UpdateFunctionCodeInput
+	tags        map[string]string
+	versions    []string
+
+	invokeInput  *lambda.InvokeInput
+	invokeOutput *lambda.InvokeOutput
+
+	code        *lambda.FunctionCodeLocation
+	reserved    *int64
+	aliases     []*lambda.AliasConfiguration
+	provisioned []*lambda.ProvisionedConcurrencyConfigListItem
+
+	// provisionedStatus is reported by successive polls of provisioned
+	// concurrency, the last one repeating
+	provisionedStatus []string
+	provisionedReason string
+
+	// onUpdate runs before every update call, e.g. to simulate a
+	// concurrent change to the function.
+	onUpdate func(f *fakeLambda)
+}
+
+func newFakeLambda() *fakeLambda {
+	return &fakeLambda{
+		config: &lambda.FunctionConfiguration{
+			FunctionName:     aws.String("test"),
+			FunctionArn:      aws.String("arn:aws:lambda:us-east-1:123456789012:function:test"),
+			State:            aws.String(lambda.StateActive),
+			LastUpdateStatus: aws.String(lambda.LastUpdateStatusSuccessful),
+			RevisionId:       aws.String("1"),
+		},
+		tags: map[string]string{},
+	}
+}
+
+func (f *fakeLambda) GetFunctionConfigurationWithContext(
+	aws.Context, *lambda.GetFunctionConfigurationInput, ...request.Option,
+) (*lambda.FunctionConfiguration, error) {
+	c := *f.config
+	return &c, nil
+}
+
+// update bumps the revision of the function after checking the expected
+// revision id, like the Lambda API does.
+func (f *fakeLambda) update(
+	op string, revisionID *string, apply func(c *lambda.FunctionConfiguration),
+) (*lambda.FunctionConfiguration, error) {
+	f.calls = append(f.calls, op)
+	if f.onUpdate != nil {
+		f.onUpdate(f)
+	}
+	if revisionID != nil && *revisionID != aws.StringValue(f.config.RevisionId) {
+		return nil, awserr.New(lambda.ErrCodePreconditionFailedException, "revision id mismatch", nil)
+	}
+
+	c := *f.config
+	apply(&c)
+	f.config = &c
+	f.bump()
+
+	output := *f.config
+	return &output, nil
+}
+
+func (f *fakeLambda) bump() {
+	rev, _ := strconv.Atoi(aws.StringValue(f.config.RevisionId))
+	f.config.RevisionId = aws.String(strconv.Itoa(rev + 1))
+}
+
+func (f *fakeLambda) UpdateFunctionConfigurationWithContext(
+	_ aws.Context, input *lambda.UpdateFunctionConfigurationInput, _ ...request.Option,
+) (*lambda.FunctionConfiguration, error) {
+	f.configInput = input
+	return f.update("UpdateFunctionConfiguration", input.RevisionId, func(c *lambda.FunctionConfiguration) {
+		if input.MemorySize != nil {
+			c.MemorySize = input.MemorySize
+		}
+		if input.Description != nil {
+			c.Description = input.Description
+		}
+		if input.Environment != nil {
+			c.Environment = &lambda.EnvironmentResponse{Variables: input.Environment.Variables}
+		}
+		if input.EphemeralStorage != nil {
+			c.EphemeralStorage = input.EphemeralStorage
+		}
+		if input.SnapStart != nil {
+			c.SnapStart = &lambda.SnapStartResponse{
+				ApplyOn:            input.SnapStart.ApplyOn,
+				OptimizationStatus: aws.String(lambda.SnapStartOptimizationStatusOff),
+			}
+		}
+	})
+}
+
+func (f *fakeLambda) UpdateFunctionCodeWithContext(
+	_ aws.Context, input *lambda.UpdateFunctionCodeInput, _ ...request.Option,
+) (*lambda.FunctionConfiguration, error) {
+	f.codeInput = input
+	return f.update("UpdateFunctionCode", input.RevisionId, func(c *lambda.FunctionConfiguration) {
+		c.CodeSha256 = aws.String("sha-" + aws.StringValue(c.RevisionId))
+		c.CodeSize = aws.Int64(int64(len(input.ZipFile)))
+		if aws.BoolValue(input.Publish) {
+			f.versions = append(f.versions, strconv.Itoa(len(f.versions)+1))
+			c.Version = aws.String(f.versions[len(f.versions)-1])
+		}
+	})
+}
+
+func (f *fakeLambda) ListVersionsByFunctionPagesWithContext(
+	_ aws.Context,
+	_ *lambda.ListVersionsByFunctionInput,
+	fn func(*lambda.ListVersionsByFunctionOutput, bool) bool,
+	_ ...request.Option,
+) error {
+	page := &lambda.ListVersionsByFunctionOutput{
+		Versions: []*lambda.FunctionConfiguration{{Version: aws.String("$LATEST")}},
+	}
+	for _, version := range f.versions {
+		page.Versions = append(page.Versions, &lambda.FunctionConfiguration{Version: aws.String(version)})
+	}
+	fn(page, true)
+	return nil
+}
+
+func (f *fakeLambda) TagResourceWithContext(
+	_ aws.Context, input *lambda.TagResourceInput, _ ...request.Option,
+) (*lambda.TagResourceOutput, error) {
+	if aws.StringValue(input.Resource) != aws.StringValue(f.config.FunctionArn) {
+		return nil, awserr.New(lambda.ErrCodeInvalidParameterValueException, "qualified arn", nil)
+	}
+	maps.Copy(f.tags, aws.StringValueMap(input.Tags))
+	return &lambda.TagResourceOutput{}, nil
+}
+
+func (f *fakeLambda) ListTagsWithContext(
+	aws.Context, *lambda.ListTagsInput, ...request.Option,
+) (*lambda.ListTagsOutput, error) {
+	return &lambda.ListTagsOutput{Tags: aws.StringMap(f.tags)}, nil
+}
+
+func (f *fakeLambda) UntagResourceWithContext(
+	_ aws.Context, input *lambda.UntagResourceInput, _ ...request.Option,
+) (*lambda.UntagResourceOutput, error) {
+	for _, key := range input.TagKeys {
+		delete(f.tags, aws.StringValue(key))
+	}
+	return &lambda.UntagResourceOutput{}, nil
+}
+
+func TestPlugin_deploy_revision(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		modify   bool
+		wantKind ErrorKind
+	}{
+		{
+			name:   "no revision check",
+			config: Config{},
+			modify: true,
+		},
+		{
+			name:   "expect unchanged",
+			config: Config{ExpectUnchanged: true},
+		},
+		{
+			name:     "expect unchanged with concurrent change",
+			config:   Config{ExpectUnchanged: true},
+			modify:   true,
+			wantKind: KindConflict,
+		},
+		{
+			name:   "user supplied revision id",
+			config: Config{ReversionID: "1"},
+		},
+		{
+			name:     "stale user supplied revision id",
+			config:   Config{ReversionID: "0"},
+			wantKind: KindConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeLambda()
+			if tt.modify {
+				svc.onUpdate = func(f *fakeLambda) {
+					if len(f.calls) == 2 {
+						f.bump()
+					}
+				}
+			}
+
+			tt.config.FunctionName = "test"
+			p := &Plugin{Config: tt.config}
+			err := p.deploy(
+				context.Background(),
+				svc,
+				&lambda.UpdateFunctionConfigurationInput{FunctionName: aws.String("test")},
+				&lambda.UpdateFunctionCodeInput{FunctionName: aws.String("test")},
+			)
+
+			if tt.wantKind == 0 {
+				if err != nil {
+					t.Fatalf("deploy() error = %v", err)
+				}
+				return
+			}
+			var e *Error
+			if !errors.As(err, &e) || e.Kind != tt.wantKind {
+				t.Fatalf("deploy() error = %v, want kind %v", err, tt.wantKind)
+			}
+			if !strings.Contains(e.Hint, "modified by someone else") {
+				t.Errorf("deploy() hint = %q", e.Hint)
+			}
+		})
+	}
+}
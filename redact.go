@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// defaultSecretPatterns match environment variable names whose values
+// are masked in debug output.
+var defaultSecretPatterns = []string{"*_KEY", "*PASSWORD*", "*TOKEN*", "*SECRET*"}
+
+// isSecretKey reports whether the value of the environment variable key
+// must be masked.
+func (p Plugin) isSecretKey(key string) bool {
+	if p.secretKeys[key] {
+		return true
+	}
+
+	key = strings.ToUpper(key)
+	for _, pattern := range p.Config.SecretPatterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), key); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// maskURL keeps the scheme and host of a webhook url and masks the rest,
+// since incoming webhook urls are credentials.
+func maskURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return mask(value)
+	}
+	return u.Scheme + "://" + u.Host + "/" + maskedValue
+}
+
+func maskURLs(values []string) []string {
+	output := make([]string, 0, len(values))
+	for _, value := range values {
+		output = append(output, maskURL(strings.TrimSpace(value)))
+	}
+	return output
+}
+
+// redact returns a copy of val with credentials and secret environment
+// values masked, so it can be dumped safely.
+func (p Plugin) redact(val any) any {
+	switch v := val.(type) {
+	case Config:
+		return p.redactConfig(v)
+	case *Config:
+		c := p.redactConfig(*v)
+		return &c
+	case *lambda.FunctionConfiguration:
+		return p.redactFunctionConfiguration(v)
+	case *lambda.UpdateFunctionConfigurationInput:
+		return p.redactConfigurationInput(v)
+	}
+
+	return val
+}
+
+func (p Plugin) redactConfig(c Config) Config {
+	c.AccessKey = mask(c.AccessKey)
+	c.SecretKey = mask(c.SecretKey)
+	c.SessionToken = mask(c.SessionToken)
+	c.WebIdentityToken = mask(c.WebIdentityToken)
+	c.NotifyWebhooks = maskURLs(c.NotifyWebhooks)
+	c.NotifySlack = maskURLs(c.NotifySlack)
+
+	// OTLP headers usually carry the collector's authentication
+	headers := make([]string, 0, len(c.OTLPHeaders))
+	for _, h := range c.OTLPHeaders {
+		if key, value, ok := strings.Cut(h, "="); ok {
+			h = key + "=" + mask(value)
+		}
+		headers = append(headers, h)
+	}
+	c.OTLPHeaders = headers
+
+	envs := make([]string, 0, len(c.Environment))
+	for _, e := range c.Environment {
+		if key, _, ok := strings.Cut(e, "="); ok && p.isSecretKey(strings.TrimSpace(key)) {
+			e = key + "=" + maskedValue
+		}
+		envs = append(envs, e)
+	}
+	c.Environment = envs
+
+	return c
+}
+
+func (p Plugin) redactVariables(variables map[string]*string) map[string]*string {
+	output := make(map[string]*string, len(variables))
+	for key, value := range variables {
+		if p.isSecretKey(key) {
+			value = aws.String(mask(aws.StringValue(value)))
+		}
+		output[key] = value
+	}
+	return output
+}
+
+func (p Plugin) redactFunctionConfiguration(c *lambda.FunctionConfiguration) *lambda.FunctionConfiguration {
+	if c == nil || c.Environment == nil {
+		return c
+	}
+
+	redacted := *c
+	env := *c.Environment
+	env.Variables = p.redactVariables(c.Environment.Variables)
+	redacted.Environment = &env
+
+	return &redacted
+}
+
+func (p Plugin) redactConfigurationInput(
+	c *lambda.UpdateFunctionConfigurationInput,
+) *lambda.UpdateFunctionConfigurationInput {
+	if c == nil || c.Environment == nil {
+		return c
+	}
+
+	redacted := *c
+	env := *c.Environment
+	env.Variables = p.redactVariables(c.Environment.Variables)
+	redacted.Environment = &env
+
+	return &redacted
+}
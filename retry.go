@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+var (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// isRetryable reports whether err is a transient conflict or throttling
+// error that is worth retrying once the function is idle again.
+func isRetryable(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	switch aerr.Code() {
+	case lambda.ErrCodeResourceConflictException,
+		lambda.ErrCodeTooManyRequestsException:
+		return true
+	}
+
+	return false
+}
+
+// backoff returns the delay before the given retry attempt, using
+// exponential backoff with jitter.
+func backoff(attempt int) time.Duration {
+	d := retryBaseDelay << attempt
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+
+	return d/2 + rand.N(d/2+1)
+}
+
+// retry waits for the function to be ready and calls fn, retrying
+// conflicting or throttled calls up to Config.MaxRetries times.
+func (p *Plugin) retry(ctx context.Context, svc lambdaiface.LambdaAPI, op string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := p.checkStatus(ctx, svc); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if !isRetryable(err) || attempt >= p.Config.MaxRetries {
+			return wrapError(op, err)
+		}
+
+		delay := backoff(attempt)
+		p.logger().Warn(op+" failed, retrying",
+			"phase", "retry",
+			"error", err,
+			"delay", delay.Round(time.Millisecond),
+			"attempt", attempt+1,
+			"max_retries", p.Config.MaxRetries,
+		)
+
+		select {
+		case <-ctx.Done():
+			return wrapError(op, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func TestPlugin_retry(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = time.Second })
+
+	conflict := awserr.New(lambda.ErrCodeResourceConflictException, "update in progress", nil)
+	throttled := awserr.New(lambda.ErrCodeTooManyRequestsException, "rate exceeded", nil)
+	invalid := awserr.New(lambda.ErrCodeInvalidParameterValueException, "bad value", nil)
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		errs       []error
+		wantCalls  int
+		wantKind   ErrorKind
+	}{
+		{
+			name:       "success",
+			maxRetries: 3,
+			wantCalls:  1,
+		},
+		{
+			name:       "recovers from conflict and throttling",
+			maxRetries: 3,
+			errs:       []error{conflict, throttled},
+			wantCalls:  3,
+		},
+		{
+			name:       "gives up after max retries",
+			maxRetries: 2,
+			errs:       []error{conflict, conflict, conflict, conflict},
+			wantCalls:  3,
+			wantKind:   KindConflict,
+		},
+		{
+			name:       "does not retry other errors",
+			maxRetries: 3,
+			errs:       []error{invalid},
+			wantCalls:  1,
+			wantKind:   KindInvalidConfig,
+		},
+		{
+			name:       "retries disabled",
+			maxRetries: 0,
+			errs:       []error{throttled},
+			wantCalls:  1,
+			wantKind:   KindThrottled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{Config: Config{FunctionName: "test", MaxRetries: tt.maxRetries}}
+			calls := 0
+			err := p.retry(context.Background(), newFakeLambda(), "update function code", func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("retry() calls = %d, want %d", calls, tt.wantCalls)
+			}
+
+			if tt.wantKind == 0 {
+				if err != nil {
+					t.Fatalf("retry() error = %v", err)
+				}
+				return
+			}
+			var e *Error
+			if !errors.As(err, &e) || e.Kind != tt.wantKind {
+				t.Errorf("retry() error = %v, want kind %v", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func Test_backoff(t *testing.T) {
+	for attempt := range 10 {
+		d := backoff(attempt)
+		if d <= 0 || d > retryMaxDelay {
+			t.Errorf("backoff(%d) = %s, want (0, %s]", attempt, d, retryMaxDelay)
+		}
+	}
+}
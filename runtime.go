@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+func (p Plugin) validateRuntimeManagement() error {
+	mode, arn := p.Config.RuntimeUpdateMode, p.Config.RuntimeVersionArn
+	if mode != "" && !slices.Contains(lambda.UpdateRuntimeOn_Values(), mode) {
+		return configError("invalid runtime update mode %q, must be one of %v", mode, lambda.UpdateRuntimeOn_Values())
+	}
+	if arn != "" && mode != lambda.UpdateRuntimeOnManual {
+		return configError("runtime version arn requires runtime update mode %q", lambda.UpdateRuntimeOnManual)
+	}
+	if arn == "" && mode == lambda.UpdateRuntimeOnManual {
+		return configError("runtime update mode %q requires a runtime version arn", lambda.UpdateRuntimeOnManual)
+	}
+
+	return nil
+}
+
+// putRuntimeManagement sets when the runtime of the function is updated,
+// or pins it to a runtime version. It runs before the code update, so the
+// published version uses the runtime it configures.
+//
+// The call has no revision id precondition but changes the revision of the
+// function, so a given revisionID is checked right before it and the
+// revision id to pass on is returned.
+func (p *Plugin) putRuntimeManagement(
+	ctx context.Context, svc lambdaiface.LambdaAPI, revisionID string,
+) (string, error) {
+	if p.Config.RuntimeUpdateMode == "" || p.Config.DryRun {
+		return revisionID, nil
+	}
+
+	if revisionID != "" {
+		current, err := p.revision(ctx, svc)
+		if err != nil {
+			return "", err
+		}
+		if current != revisionID {
+			return "", revisionConflict(wrapError("put runtime management config", awserr.New(
+				lambda.ErrCodePreconditionFailedException, "the revision id does not match "+current, nil,
+			)), revisionID)
+		}
+	}
+
+	input := &lambda.PutRuntimeManagementConfigInput{
+		FunctionName:    aws.String(p.Config.FunctionName),
+		UpdateRuntimeOn: aws.String(p.Config.RuntimeUpdateMode),
+	}
+	if p.Config.RuntimeVersionArn != "" {
+		input.SetRuntimeVersionArn(p.Config.RuntimeVersionArn)
+	}
+
+	ctx, done := p.phase(ctx, "runtime", "put runtime management config")
+	var output *lambda.PutRuntimeManagementConfigOutput
+	err := p.retry(ctx, svc, "put runtime management config", func() (err error) {
+		output, err = svc.PutRuntimeManagementConfigWithContext(ctx, input)
+		return err
+	})
+	done(err)
+	if err != nil {
+		return "", err
+	}
+
+	p.logger().Info("runtime management config updated",
+		"phase", "runtime",
+		"update_runtime_on", aws.StringValue(output.UpdateRuntimeOn),
+		"runtime_version_arn", aws.StringValue(output.RuntimeVersionArn),
+	)
+
+	if revisionID == "" {
+		return "", nil
+	}
+	return p.revision(ctx, svc)
+}
+
+// revision returns the current revision id of the function.
+func (p *Plugin) revision(ctx context.Context, svc lambdaiface.LambdaAPI) (string, error) {
+	c, err := svc.GetFunctionConfigurationWithContext(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+	})
+	if err != nil {
+		return "", wrapError("get function configuration", err)
+	}
+	return aws.StringValue(c.RevisionId), nil
+}
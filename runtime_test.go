@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func (f *fakeLambda) PutRuntimeManagementConfigWithContext(
+	_ aws.Context, input *lambda.PutRuntimeManagementConfigInput, _ ...request.Option,
+) (*lambda.PutRuntimeManagementConfigOutput, error) {
+	// the config has no revision id, but it changes the one of the function
+	if _, err := f.update("PutRuntimeManagementConfig", nil, func(*lambda.FunctionConfiguration) {}); err != nil {
+		return nil, err
+	}
+	return &lambda.PutRuntimeManagementConfigOutput{
+		FunctionArn:       f.config.FunctionArn,
+		UpdateRuntimeOn:   input.UpdateRuntimeOn,
+		RuntimeVersionArn: input.RuntimeVersionArn,
+	}, nil
+}
+
+func TestPlugin_validateRuntimeManagement(t *testing.T) {
+	const arn = "arn:aws:lambda:us-east-1::runtime:0123456789abcdef"
+	tests := []struct {
+		name    string
+		mode    string
+		arn     string
+		wantErr bool
+	}{
+		{name: "unset"},
+		{name: "auto", mode: lambda.UpdateRuntimeOnAuto},
+		{name: "function update", mode: lambda.UpdateRuntimeOnFunctionUpdate},
+		{name: "manual", mode: lambda.UpdateRuntimeOnManual, arn: arn},
+		{name: "unknown mode", mode: "Never", wantErr: true},
+		{name: "manual without arn", mode: lambda.UpdateRuntimeOnManual, wantErr: true},
+		{name: "arn without manual", mode: lambda.UpdateRuntimeOnAuto, arn: arn, wantErr: true},
+		{name: "arn without mode", arn: arn, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Plugin{Config: Config{RuntimeUpdateMode: tt.mode, RuntimeVersionArn: tt.arn}}
+			err := p.validateRuntimeManagement()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRuntimeManagement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && exitCode(err) != int(KindInvalidConfig) {
+				t.Errorf("exit code = %d, want %d", exitCode(err), KindInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestPlugin_deploy_runtimeManagement(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		cfg    bool
+		// concurrent changes the function right after the configuration
+		// update, like someone else in the console
+		concurrent bool
+		wantCalls  []string
+		wantKind   ErrorKind
+	}{
+		{
+			name:      "unset",
+			config:    Config{},
+			wantCalls: []string{"UpdateFunctionCode"},
+		},
+		{
+			name:      "before the code update",
+			config:    Config{RuntimeUpdateMode: lambda.UpdateRuntimeOnFunctionUpdate},
+			wantCalls: []string{"PutRuntimeManagementConfig", "UpdateFunctionCode"},
+		},
+		{
+			name:      "revision id is captured after",
+			config:    Config{RuntimeUpdateMode: lambda.UpdateRuntimeOnAuto, ExpectUnchanged: true},
+			wantCalls: []string{"PutRuntimeManagementConfig", "UpdateFunctionCode"},
+		},
+		{
+			name:      "before the configuration update",
+			config:    Config{RuntimeUpdateMode: lambda.UpdateRuntimeOnAuto, ExpectUnchanged: true},
+			cfg:       true,
+			wantCalls: []string{"PutRuntimeManagementConfig", "UpdateFunctionConfiguration", "UpdateFunctionCode"},
+		},
+		{
+			name:       "concurrent change is detected",
+			config:     Config{RuntimeUpdateMode: lambda.UpdateRuntimeOnAuto, ExpectUnchanged: true},
+			cfg:        true,
+			concurrent: true,
+			wantCalls:  []string{"PutRuntimeManagementConfig", "UpdateFunctionConfiguration", "UpdateFunctionCode"},
+			wantKind:   KindConflict,
+		},
+		{
+			name:      "revision id is checked before",
+			config:    Config{RuntimeUpdateMode: lambda.UpdateRuntimeOnAuto, ReversionID: "1"},
+			wantCalls: []string{"PutRuntimeManagementConfig", "UpdateFunctionCode"},
+		},
+		{
+			name:     "revision id mismatch",
+			config:   Config{RuntimeUpdateMode: lambda.UpdateRuntimeOnAuto, ReversionID: "7"},
+			wantKind: KindConflict,
+		},
+		{
+			name:      "dry run",
+			config:    Config{RuntimeUpdateMode: lambda.UpdateRuntimeOnAuto, DryRun: true},
+			wantCalls: []string{"UpdateFunctionCode"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeLambda()
+			if tt.concurrent {
+				svc.onUpdate = func(f *fakeLambda) {
+					if n := len(f.calls); n > 1 && f.calls[n-2] == "UpdateFunctionConfiguration" {
+						f.bump()
+					}
+				}
+			}
+			tt.config.FunctionName = "test"
+			tt.config.SkipTags = true
+			p := &Plugin{Config: tt.config}
+
+			var cfg *lambda.UpdateFunctionConfigurationInput
+			if tt.cfg {
+				cfg = &lambda.UpdateFunctionConfigurationInput{FunctionName: aws.String("test")}
+				cfg.SetMemorySize(256)
+			}
+			err := p.deploy(context.Background(), svc, cfg, &lambda.UpdateFunctionCodeInput{
+				FunctionName: aws.String("test"),
+			})
+			if tt.wantKind != 0 {
+				if exitCode(err) != int(tt.wantKind) {
+					t.Fatalf("deploy() error = %v, want kind %v", err, tt.wantKind)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(svc.calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", svc.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// Prefixes of environment values that reference a secret.
+const (
+	ssmPrefix            = "ssm:"
+	secretsManagerPrefix = "secretsmanager:"
+)
+
+// secretResolver looks up environment values that reference SSM
+// Parameter Store parameters or Secrets Manager secrets.
+type secretResolver struct {
+	ssm            ssmiface.SSMAPI
+	secretsManager secretsmanageriface.SecretsManagerAPI
+
+	secrets map[string]map[string]any
+}
+
+func newSecretResolver(p client.ConfigProvider) *secretResolver {
+	return &secretResolver{
+		ssm:            ssm.New(p),
+		secretsManager: secretsmanager.New(p),
+	}
+}
+
+// isSecretReference reports whether value references a secret.
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, ssmPrefix) ||
+		strings.HasPrefix(value, secretsManagerPrefix)
+}
+
+// resolve returns the value referenced by ref, either
+// ssm:<parameter-name> or secretsmanager:<secret-id>[#json-key].
+func (r *secretResolver) resolve(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, ssmPrefix):
+		return r.parameter(ctx, strings.TrimPrefix(ref, ssmPrefix))
+	case strings.HasPrefix(ref, secretsManagerPrefix):
+		id, key, _ := strings.Cut(strings.TrimPrefix(ref, secretsManagerPrefix), "#")
+		return r.secret(ctx, id, key)
+	}
+
+	return ref, nil
+}
+
+func (r *secretResolver) parameter(ctx context.Context, name string) (string, error) {
+	output, err := r.ssm.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(output.Parameter.Value), nil
+}
+
+func (r *secretResolver) secret(ctx context.Context, id, key string) (string, error) {
+	if key == "" {
+		output, err := r.secretsManager.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(id),
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(output.SecretString), nil
+	}
+
+	values, ok := r.secrets[id]
+	if !ok {
+		output, err := r.secretsManager.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(id),
+		})
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal([]byte(aws.StringValue(output.SecretString)), &values); err != nil {
+			return "", fmt.Errorf("secret %s is not a JSON object", id)
+		}
+		if r.secrets == nil {
+			r.secrets = make(map[string]map[string]any)
+		}
+		r.secrets[id] = values
+	}
+
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %q", id, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+// resolveSecrets replaces every secret reference in variables with the
+// referenced value and remembers the keys so they are never dumped.
+func (p *Plugin) resolveSecrets(ctx context.Context, r *secretResolver, variables map[string]*string) error {
+	for key, value := range variables {
+		ref := aws.StringValue(value)
+		if !isSecretReference(ref) {
+			continue
+		}
+
+		resolved, err := r.resolve(ctx, ref)
+		if err != nil {
+			return secretError(key, ref, err)
+		}
+
+		variables[key] = aws.String(resolved)
+		if p.secretKeys == nil {
+			p.secretKeys = make(map[string]bool)
+		}
+		p.secretKeys[key] = true
+	}
+
+	return nil
+}
+
+func secretError(key, ref string, err error) error {
+	e := &Error{
+		Kind: KindInvalidConfig,
+		Op:   fmt.Sprintf("resolve environment %s from %s", key, ref),
+		Err:  err,
+		Hint: "check the reference and that the deploy role can read it",
+	}
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case ssm.ErrCodeParameterNotFound, secretsmanager.ErrCodeResourceNotFoundException:
+			e.Kind = KindNotFound
+		case "AccessDeniedException", secretsmanager.ErrCodeDecryptionFailure:
+			e.Kind = KindPermissionDenied
+		default:
+			return wrapError(e.Op, err)
+		}
+	}
+
+	return e
+}
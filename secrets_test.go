@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// newSecretStub starts a local stand-in for SSM Parameter Store and
+// Secrets Manager, which share the same JSON protocol.
+func newSecretStub(t *testing.T, parameters, secrets map[string]string) *session.Session {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Error(err)
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.GetParameter":
+			if input["WithDecryption"] != true {
+				t.Errorf("GetParameter without decryption")
+			}
+			name, _ := input["Name"].(string)
+			if value, ok := parameters[name]; ok {
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"Parameter": map[string]any{"Name": name, "Value": value},
+				})
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ParameterNotFound","message":"not found"}`))
+		case "secretsmanager.GetSecretValue":
+			id, _ := input["SecretId"].(string)
+			if value, ok := secrets[id]; ok {
+				_ = json.NewEncoder(w).Encode(map[string]any{"Name": id, "SecretString": value})
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+		default:
+			t.Errorf("unexpected target %q", r.Header.Get("X-Amz-Target"))
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+}
+
+func TestPlugin_resolveSecrets(t *testing.T) {
+	sess := newSecretStub(t,
+		map[string]string{"/prod/db/pass": "s3cr3t"},
+		map[string]string{
+			"prod/api":   `{"key":"api-key","port":8080}`,
+			"prod/plain": "plain-secret",
+		},
+	)
+
+	p := &Plugin{}
+	variables := aws.StringMap(map[string]string{
+		"DB_PASS":  "ssm:/prod/db/pass",
+		"API_KEY":  "secretsmanager:prod/api#key",
+		"API_PORT": "secretsmanager:prod/api#port",
+		"PLAIN":    "secretsmanager:prod/plain",
+		"VERSION":  "1.0.0",
+	})
+	if err := p.resolveSecrets(context.Background(), newSecretResolver(sess), variables); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"DB_PASS":  "s3cr3t",
+		"API_KEY":  "api-key",
+		"API_PORT": "8080",
+		"PLAIN":    "plain-secret",
+		"VERSION":  "1.0.0",
+	}
+	if got := aws.StringValueMap(variables); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveSecrets() = %v, want %v", got, want)
+	}
+
+	masked := p.redact(&lambda.FunctionConfiguration{
+		Environment: &lambda.EnvironmentResponse{Variables: variables},
+	}).(*lambda.FunctionConfiguration)
+	for key, value := range aws.StringValueMap(masked.Environment.Variables) {
+		if key == "VERSION" {
+			if value != "1.0.0" {
+				t.Errorf("redact() masked %s", key)
+			}
+			continue
+		}
+		if value != maskedValue {
+			t.Errorf("redact() leaked %s=%s", key, value)
+		}
+	}
+	if aws.StringValue(variables["DB_PASS"]) != "s3cr3t" {
+		t.Errorf("redact() modified the original configuration")
+	}
+}
+
+func TestPlugin_resolveSecrets_errors(t *testing.T) {
+	sess := newSecretStub(t, nil, map[string]string{"prod/plain": "plain-secret"})
+
+	tests := []struct {
+		name string
+		ref  string
+		want ErrorKind
+	}{
+		{name: "missing parameter", ref: "ssm:/prod/missing", want: KindNotFound},
+		{name: "missing secret", ref: "secretsmanager:prod/missing", want: KindNotFound},
+		{name: "not a json secret", ref: "secretsmanager:prod/plain#key", want: KindInvalidConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plugin{}
+			err := p.resolveSecrets(
+				context.Background(),
+				newSecretResolver(sess),
+				aws.StringMap(map[string]string{"SECRET": tt.ref}),
+			)
+			var e *Error
+			if !errors.As(err, &e) || e.Kind != tt.want {
+				t.Errorf("resolveSecrets() error = %v, want kind %v", err, tt.want)
+			}
+		})
+	}
+}
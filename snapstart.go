@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// snapStartEnabled reports whether published versions of the function
+// are optimized with SnapStart.
+func snapStartEnabled(c *lambda.FunctionConfiguration) bool {
+	return c.SnapStart != nil &&
+		aws.StringValue(c.SnapStart.ApplyOn) == lambda.SnapStartApplyOnPublishedVersions
+}
+
+// waitSnapStart waits until the snapshot of the published version is
+// created, so a deploy only succeeds once the version can be invoked.
+func (p *Plugin) waitSnapStart(ctx context.Context, svc lambdaiface.LambdaAPI, version string) error {
+	ctx, done := p.phase(ctx, "snapstart", "wait for snap start optimization")
+	err := p.snapStartReady(ctx, svc, version)
+	done(err)
+
+	return err
+}
+
+func (p *Plugin) snapStartReady(ctx context.Context, svc lambdaiface.LambdaAPI, version string) error {
+	input := &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+		Qualifier:    aws.String(version),
+	}
+	c, err := svc.GetFunctionConfigurationWithContext(ctx, input)
+	if err != nil {
+		return wrapError("get function configuration", err)
+	}
+
+	if aws.StringValue(c.State) != lambda.StateActive {
+		start := time.Now()
+		err := svc.WaitUntilFunctionActiveV2WithContext(
+			ctx,
+			&lambda.GetFunctionInput{
+				FunctionName: aws.String(p.Config.FunctionName),
+				Qualifier:    aws.String(version),
+			},
+			request.WithWaiterMaxAttempts(p.Config.MaxAttempts),
+			p.waiterProgress("waiting for snap start optimization"),
+		)
+		p.waited += time.Since(start)
+		if err != nil {
+			return snapStartFailed(ctx, svc, input, wrapError("wait for snap start", err))
+		}
+
+		if c, err = svc.GetFunctionConfigurationWithContext(ctx, input); err != nil {
+			return wrapError("get function configuration", err)
+		}
+	}
+
+	status := ""
+	if c.SnapStart != nil {
+		status = aws.StringValue(c.SnapStart.OptimizationStatus)
+	}
+	if status != lambda.SnapStartOptimizationStatusOn {
+		return &Error{
+			Kind: KindUnknown,
+			Op:   "wait for snap start",
+			Err:  fmt.Errorf("optimization status of version %s is %q", version, status),
+			Hint: "check that the runtime supports SnapStart",
+		}
+	}
+
+	p.logger().Info("snap start optimization done", "phase", "snapstart", "function_version", version)
+	return nil
+}
+
+// snapStartFailed adds the reason of a failed snapshot to err.
+func snapStartFailed(
+	ctx context.Context, svc lambdaiface.LambdaAPI, input *lambda.GetFunctionConfigurationInput, err error,
+) error {
+	c, getErr := svc.GetFunctionConfigurationWithContext(ctx, input)
+	if getErr != nil || aws.StringValue(c.State) != lambda.StateFailed {
+		return err
+	}
+
+	if e, ok := err.(*Error); ok {
+		e.Hint = fmt.Sprintf("%s: %s",
+			aws.StringValue(c.StateReasonCode), aws.StringValue(c.StateReason))
+	}
+	return err
+}
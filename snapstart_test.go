@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+// snapStartLambda reports published versions as pending until they are
+// waited for, then with the given state and optimization status.
+type snapStartLambda struct {
+	*fakeLambda
+
+	state   string
+	status  string
+	reason  string
+	waited  bool
+	waitErr error
+}
+
+func (f *snapStartLambda) GetFunctionConfigurationWithContext(
+	ctx aws.Context, input *lambda.GetFunctionConfigurationInput, opts ...request.Option,
+) (*lambda.FunctionConfiguration, error) {
+	c, err := f.fakeLambda.GetFunctionConfigurationWithContext(ctx, input, opts...)
+	if err != nil || aws.StringValue(input.Qualifier) == "" {
+		return c, err
+	}
+
+	c.Version = input.Qualifier
+	c.State = aws.String(lambda.StatePending)
+	c.SnapStart = &lambda.SnapStartResponse{
+		ApplyOn:            aws.String(lambda.SnapStartApplyOnPublishedVersions),
+		OptimizationStatus: aws.String(lambda.SnapStartOptimizationStatusOff),
+	}
+	if f.waited {
+		c.State = aws.String(f.state)
+		c.StateReasonCode = aws.String(lambda.StateReasonCodeFunctionError)
+		c.StateReason = aws.String(f.reason)
+		c.SnapStart.OptimizationStatus = aws.String(f.status)
+	}
+	return c, nil
+}
+
+func (f *snapStartLambda) WaitUntilFunctionActiveV2WithContext(
+	_ aws.Context, input *lambda.GetFunctionInput, _ ...request.WaiterOption,
+) error {
+	f.calls = append(f.calls, "WaitUntilFunctionActiveV2:"+aws.StringValue(input.Qualifier))
+	f.waited = true
+	return f.waitErr
+}
+
+func TestPlugin_deploy_snapStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		svc      *snapStartLambda
+		publish  bool
+		wantErr  string
+		wantWait bool
+	}{
+		{
+			name:     "optimized",
+			svc:      &snapStartLambda{state: lambda.StateActive, status: lambda.SnapStartOptimizationStatusOn},
+			publish:  true,
+			wantWait: true,
+		},
+		{
+			name:    "not published",
+			svc:     &snapStartLambda{state: lambda.StateActive, status: lambda.SnapStartOptimizationStatusOn},
+			publish: false,
+		},
+		{
+			name:     "not optimized",
+			svc:      &snapStartLambda{state: lambda.StateActive, status: lambda.SnapStartOptimizationStatusOff},
+			publish:  true,
+			wantErr:  "optimization status of version 1",
+			wantWait: true,
+		},
+		{
+			name: "snapshot failed",
+			svc: &snapStartLambda{
+				state:   lambda.StateFailed,
+				reason:  "init timed out",
+				waitErr: awserr.New(request.WaiterResourceNotReadyErrorCode, "failure state", nil),
+			},
+			publish:  true,
+			wantErr:  "FunctionError: init timed out",
+			wantWait: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.svc.fakeLambda = newFakeLambda()
+			p := &Plugin{Config: Config{
+				FunctionName: "test",
+				SnapStart:    lambda.SnapStartApplyOnPublishedVersions,
+				SkipTags:     true,
+			}}
+
+			err := p.deploy(context.Background(), tt.svc, p.configurationInput(nil), &lambda.UpdateFunctionCodeInput{
+				FunctionName: aws.String("test"),
+				Publish:      aws.Bool(tt.publish),
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("deploy() error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.svc.waited != tt.wantWait {
+				t.Errorf("waited = %v, want %v", tt.svc.waited, tt.wantWait)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// Change is a configuration field changed by the deploy.
+type Change struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Summary describes what a deploy changed, for the Drone card and the
+// GitHub Actions job summary.
+type Summary struct {
+	FunctionName    string
+	Region          string
+	PreviousVersion string
+	Version         string
+	CodeSize        int64
+	Changes         []Change
+	Waited          time.Duration
+	DryRun          bool
+}
+
+func (p *Plugin) summaryEnabled() bool {
+	return p.Config.CardPath != "" || p.Config.StepSummary != ""
+}
+
+// previousVersion returns the highest published version other than the
+// one that was just deployed.
+func (p *Plugin) previousVersion(ctx context.Context, svc lambdaiface.LambdaAPI) (string, error) {
+	latest := -1
+	err := svc.ListVersionsByFunctionPagesWithContext(ctx, &lambda.ListVersionsByFunctionInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+	}, func(page *lambda.ListVersionsByFunctionOutput, _ bool) bool {
+		for _, v := range page.Versions {
+			version := aws.StringValue(v.Version)
+			if version == p.result.Version {
+				continue
+			}
+			if n, err := strconv.Atoi(version); err == nil && n > latest {
+				latest = n
+			}
+		}
+		return true
+	})
+	if err != nil || latest < 0 {
+		return "", err
+	}
+
+	return strconv.Itoa(latest), nil
+}
+
+func joinValues(values []*string) string {
+	return strings.Join(aws.StringValueSlice(values), ", ")
+}
+
+func layerArns(layers []*lambda.Layer) string {
+	arns := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		arns = append(arns, aws.StringValue(layer.Arn))
+	}
+	return strings.Join(arns, ", ")
+}
+
+// functionFields returns the configuration fields shown in the summary.
+func functionFields(c *lambda.FunctionConfiguration) map[string]string {
+	fields := map[string]string{
+		"MemorySize":    strconv.FormatInt(aws.Int64Value(c.MemorySize), 10),
+		"Timeout":       strconv.FormatInt(aws.Int64Value(c.Timeout), 10),
+		"Handler":       aws.StringValue(c.Handler),
+		"Role":          aws.StringValue(c.Role),
+		"Runtime":       aws.StringValue(c.Runtime),
+		"Description":   aws.StringValue(c.Description),
+		"Layers":        layerArns(c.Layers),
+		"Architectures": joinValues(c.Architectures),
+	}
+	if c.VpcConfig != nil {
+		fields["Subnets"] = joinValues(c.VpcConfig.SubnetIds)
+		fields["SecurityGroups"] = joinValues(c.VpcConfig.SecurityGroupIds)
+	}
+	if c.TracingConfig != nil {
+		fields["TracingMode"] = aws.StringValue(c.TracingConfig.Mode)
+	}
+	if c.EphemeralStorage != nil {
+		fields["EphemeralStorage"] = strconv.FormatInt(aws.Int64Value(c.EphemeralStorage.Size), 10)
+	}
+	if c.SnapStart != nil {
+		fields["SnapStart"] = aws.StringValue(c.SnapStart.ApplyOn)
+	}
+	if c.LoggingConfig != nil {
+		fields["LogFormat"] = aws.StringValue(c.LoggingConfig.LogFormat)
+		fields["LogGroup"] = aws.StringValue(c.LoggingConfig.LogGroup)
+	}
+	return fields
+}
+
+var summaryFields = []string{
+	"MemorySize", "Timeout", "Handler", "Role", "Runtime", "Description",
+	"Layers", "Architectures", "Subnets", "SecurityGroups", "TracingMode",
+	"EphemeralStorage", "SnapStart", "LogFormat", "LogGroup",
+}
+
+// configChanges returns the fields that differ between before and after.
+func configChanges(before, after *lambda.FunctionConfiguration) []Change {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	var changes []Change
+	b, a := functionFields(before), functionFields(after)
+	for _, field := range summaryFields {
+		if b[field] != a[field] {
+			changes = append(changes, Change{Field: field, Before: b[field], After: a[field]})
+		}
+	}
+
+	// only the keys of environment variables are shown, never the values
+	var env [2]map[string]string
+	for i, c := range []*lambda.FunctionConfiguration{before, after} {
+		if c.Environment != nil {
+			env[i] = aws.StringValueMap(c.Environment.Variables)
+		}
+	}
+	added, changed, removed := diffEnvironment(env[0], env[1])
+	var diff []string
+	for _, keys := range []struct {
+		label string
+		keys  []string
+	}{{"added", added}, {"changed", changed}, {"removed", removed}} {
+		if len(keys.keys) > 0 {
+			diff = append(diff, keys.label+" "+strings.Join(keys.keys, ", "))
+		}
+	}
+	if len(diff) > 0 {
+		changes = append(changes, Change{
+			Field:  "Environment",
+			Before: strconv.Itoa(len(env[0])) + " variables",
+			After:  strconv.Itoa(len(env[1])) + " variables (" + strings.Join(diff, "; ") + ")",
+		})
+	}
+
+	return changes
+}
+
+func (p *Plugin) summary(ctx context.Context, svc lambdaiface.LambdaAPI) *Summary {
+	s := &Summary{
+		FunctionName: p.Config.FunctionName,
+		Region:       p.Config.Region,
+		Version:      p.result.Version,
+		CodeSize:     p.result.CodeSize,
+		Waited:       p.waited,
+		DryRun:       p.result.DryRun,
+		Changes:      configChanges(p.previous, p.configured),
+	}
+
+	if p.previous != nil {
+		if before := aws.StringValue(p.previous.CodeSha256); before != p.result.CodeSha256 {
+			s.Changes = append(s.Changes, Change{Field: "CodeSha256", Before: before, After: p.result.CodeSha256})
+		}
+	}
+
+	version, err := p.previousVersion(ctx, svc)
+	if err != nil {
+		p.logger().Warn("unable to list function versions", "phase", "summary", "error", err)
+	}
+	s.PreviousVersion = version
+
+	return s
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func (s *Summary) versionChange() string {
+	previous := s.PreviousVersion
+	if previous == "" {
+		previous = "-"
+	}
+	return previous + " → " + s.Version
+}
+
+func (s *Summary) facts() [][2]string {
+	return [][2]string{
+		{"Function", s.FunctionName},
+		{"Region", s.Region},
+		{"Version", s.versionChange()},
+		{"Code size", formatSize(s.CodeSize)},
+		{"Waited", s.Waited.Round(time.Second).String()},
+		{"Dry run", strconv.FormatBool(s.DryRun)},
+	}
+}
+
+func escapeMarkdown(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+}
+
+// Markdown renders the summary as a GitHub Actions job summary.
+func (s *Summary) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Lambda deploy: %s\n\n", escapeMarkdown(s.FunctionName))
+	b.WriteString("| | |\n| --- | --- |\n")
+	for _, fact := range s.facts() {
+		fmt.Fprintf(&b, "| %s | %s |\n", fact[0], escapeMarkdown(fact[1]))
+	}
+
+	if len(s.Changes) > 0 {
+		b.WriteString("\n| Field | Before | After |\n| --- | --- | --- |\n")
+		for _, c := range s.Changes {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", c.Field, escapeMarkdown(c.Before), escapeMarkdown(c.After))
+		}
+	}
+
+	return b.String()
+}
+
+// cardSchema is the adaptive card template that Drone renders the card
+// data with.
+const cardSchema = "https://raw.githubusercontent.com/appleboy/drone-lambda/master/card.json"
+
+// Card returns the Drone card of the summary, the data of the template at
+// cardSchema.
+func (s *Summary) Card() map[string]any {
+	facts := func(pairs [][2]string) []map[string]string {
+		output := make([]map[string]string, 0, len(pairs))
+		for _, pair := range pairs {
+			output = append(output, map[string]string{"title": pair[0], "value": pair[1]})
+		}
+		return output
+	}
+
+	changes := make([][2]string, 0, len(s.Changes))
+	for _, c := range s.Changes {
+		changes = append(changes, [2]string{c.Field, c.Before + " → " + c.After})
+	}
+
+	return map[string]any{
+		"schema": cardSchema,
+		"data": map[string]any{
+			"function": s.FunctionName,
+			"facts":    facts(s.facts()),
+			"changes":  facts(changes),
+		},
+	}
+}
+
+// writeCard writes a Drone card to path. Drone points the card path of
+// plugin steps to the standard output, where the card is read from the
+// build log as base64 inside an escape sequence.
+func writeCard(path string, card map[string]any) error {
+	content, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer
+	switch path {
+	case "/dev/stdout":
+		out = os.Stdout
+	case "/dev/stderr":
+		out = os.Stderr
+	default:
+		return os.WriteFile(path, content, 0o644)
+	}
+	_, err = io.WriteString(out, "\u001B]1338;"+base64.StdEncoding.EncodeToString(content)+"\u001B]0m\n")
+	return err
+}
+
+// writeSummary renders the deploy summary for Drone and GitHub Actions.
+func (p *Plugin) writeSummary(ctx context.Context, svc lambdaiface.LambdaAPI) error {
+	if p.result == nil || !p.summaryEnabled() {
+		return nil
+	}
+
+	s := p.summary(ctx, svc)
+
+	if p.Config.CardPath != "" {
+		if err := writeCard(p.Config.CardPath, s.Card()); err != nil {
+			return fmt.Errorf("write drone card: %w", err)
+		}
+	}
+
+	if p.Config.StepSummary != "" {
+		f, err := os.OpenFile(p.Config.StepSummary, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("write job summary: %w", err)
+		}
+		if _, err := f.WriteString(s.Markdown() + "\n"); err != nil {
+			f.Close()
+			return fmt.Errorf("write job summary: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("write job summary: %w", err)
+		}
+	}
+
+	return nil
+}
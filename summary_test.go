@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func TestPlugin_writeSummary(t *testing.T) {
+	dir := t.TempDir()
+	svc := newFakeLambda()
+	svc.versions = []string{"1", "2", "3"}
+	svc.config.MemorySize = aws.Int64(128)
+	svc.config.CodeSha256 = aws.String("old-sha")
+	svc.config.Environment = &lambda.EnvironmentResponse{
+		Variables: aws.StringMap(map[string]string{"DB_PASSWORD": "hunter2", "VERSION": "1"}),
+	}
+
+	p := &Plugin{Config: Config{
+		FunctionName:    "api",
+		Region:          "eu-west-1",
+		SkipTags:        true,
+		EnvironmentMode: EnvModeMerge,
+		CardPath:        filepath.Join(dir, "card.json"),
+		StepSummary:     filepath.Join(dir, "summary.md"),
+	}}
+	cfg := &lambda.UpdateFunctionConfigurationInput{FunctionName: aws.String("api")}
+	cfg.SetMemorySize(256)
+	cfg.SetEnvironment(&lambda.Environment{
+		Variables: aws.StringMap(map[string]string{"VERSION": "2", "DB_PASSWORD": "hunter3"}),
+	})
+	input := &lambda.UpdateFunctionCodeInput{FunctionName: aws.String("api")}
+	input.SetPublish(true)
+	input.SetZipFile(make([]byte, 2048))
+
+	ctx := context.Background()
+	if err := p.deploy(ctx, svc, cfg, input); err != nil {
+		t.Fatal(err)
+	}
+	p.waited = 12 * time.Second
+	if err := p.writeSummary(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+
+	markdown, err := os.ReadFile(p.Config.StepSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"### Lambda deploy: api",
+		"| Region | eu-west-1 |",
+		"| Version | 3 → 4 |",
+		"| Code size | 2.0 KiB |",
+		"| Waited | 12s |",
+		"| MemorySize | 128 | 256 |",
+		"| Environment | 2 variables | 2 variables (changed DB_PASSWORD, VERSION) |",
+		"| CodeSha256 | old-sha | sha-2 |",
+	} {
+		if !strings.Contains(string(markdown), want) {
+			t.Errorf("summary is missing %q:\n%s", want, markdown)
+		}
+	}
+
+	content, err := os.ReadFile(p.Config.CardPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var card struct {
+		Schema string `json:"schema"`
+		Data   struct {
+			Function string              `json:"function"`
+			Facts    []map[string]string `json:"facts"`
+			Changes  []map[string]string `json:"changes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(content, &card); err != nil {
+		t.Fatal(err)
+	}
+	if card.Schema != cardSchema || card.Data.Function != "api" {
+		t.Errorf("card = %s", content)
+	}
+	if len(card.Data.Changes) != 3 {
+		t.Errorf("card changes = %v, want 3", card.Data.Changes)
+	}
+	if !strings.Contains(string(content), `"value":"3 → 4"`) {
+		t.Errorf("card is missing the version change: %s", content)
+	}
+
+	for _, secret := range []string{"hunter2", "hunter3"} {
+		if strings.Contains(string(markdown), secret) || strings.Contains(string(content), secret) {
+			t.Errorf("summary leaked %q", secret)
+		}
+	}
+}
+
+func Test_writeCard_stdout(t *testing.T) {
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	saved := os.Stdout
+	os.Stdout = stdout
+	t.Cleanup(func() { os.Stdout = saved })
+
+	card := (&Summary{FunctionName: "api", Version: "4"}).Card()
+	if err := writeCard("/dev/stdout", card); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, ok := strings.CutPrefix(string(output), "\u001B]1338;")
+	if !ok {
+		t.Fatalf("output = %q, want the card escape sequence", output)
+	}
+	encoded, ok = strings.CutSuffix(encoded, "\u001B]0m\n")
+	if !ok {
+		t.Fatalf("output = %q, want the card escape sequence", output)
+	}
+	content, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.Marshal(card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(want) {
+		t.Errorf("card = %s, want %s", content, want)
+	}
+}
+
+func TestPlugin_writeSummary_disabled(t *testing.T) {
+	p := &Plugin{result: &Result{}}
+	if err := p.writeSummary(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_formatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:                 "0 B",
+		1023:              "1023 B",
+		1536:              "1.5 KiB",
+		5 * 1024 * 1024:   "5.0 MiB",
+		250 * 1024 * 1024: "250.0 MiB",
+	}
+	for size, want := range tests {
+		if got := formatSize(size); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
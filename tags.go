@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// managedTagPrefix marks the tags set automatically on every deploy.
+const managedTagPrefix = "drone-lambda:"
+
+// deployTags returns the configured tags plus the deployment metadata.
+func (p Plugin) deployTags(now time.Time) map[string]string {
+	tags := map[string]string{
+		managedTagPrefix + "commit-sha":   p.Commit.Sha,
+		managedTagPrefix + "author":       p.Commit.Author,
+		managedTagPrefix + "repo":         p.Commit.Repo,
+		managedTagPrefix + "build-number": p.Commit.BuildNumber,
+		managedTagPrefix + "deployed-at":  now.UTC().Format(time.RFC3339),
+		managedTagPrefix + "version":      Version,
+	}
+	maps.DeleteFunc(tags, func(_, value string) bool {
+		return value == ""
+	})
+	maps.Copy(tags, getEnvironment(trimValues(p.Config.Tags)))
+
+	return tags
+}
+
+// unqualifiedARN strips the version or alias from a function ARN, since
+// tags can only be set on the function itself.
+func unqualifiedARN(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) > 7 {
+		return strings.Join(parts[:7], ":")
+	}
+	return arn
+}
+
+// tagFunction sets the deploy tags on the function and, if enabled,
+// removes managed tags that are no longer set.
+func (p *Plugin) tagFunction(ctx context.Context, svc lambdaiface.LambdaAPI, arn string) error {
+	arn = unqualifiedARN(arn)
+	tags := p.deployTags(time.Now())
+
+	logger := p.logger().With("phase", "tag")
+	logger.Info("tag function", "tags", len(tags))
+	if _, err := svc.TagResourceWithContext(ctx, &lambda.TagResourceInput{
+		Resource: aws.String(arn),
+		Tags:     aws.StringMap(tags),
+	}); err != nil {
+		return wrapError("tag function", err)
+	}
+
+	if !p.Config.TagsPrune {
+		return nil
+	}
+
+	current, err := svc.ListTagsWithContext(ctx, &lambda.ListTagsInput{
+		Resource: aws.String(arn),
+	})
+	if err != nil {
+		return wrapError("list tags", err)
+	}
+
+	var stale []string
+	for key := range current.Tags {
+		if _, ok := tags[key]; !ok && strings.HasPrefix(key, managedTagPrefix) && key != throttleTag {
+			stale = append(stale, key)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	slices.Sort(stale)
+	logger.Info("remove stale tags", "keys", stale)
+	if _, err := svc.UntagResourceWithContext(ctx, &lambda.UntagResourceInput{
+		Resource: aws.String(arn),
+		TagKeys:  aws.StringSlice(stale),
+	}); err != nil {
+		return wrapError("untag function", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+)
+
+func TestPlugin_deployTags(t *testing.T) {
+	p := Plugin{
+		Config: Config{Tags: []string{"team=payments", "drone-lambda:repo=override"}},
+		Commit: Commit{Sha: "e5f9a8b7", Author: "appleboy", Repo: "appleboy/api"},
+	}
+
+	got := p.deployTags(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	want := map[string]string{
+		"team":                     "payments",
+		"drone-lambda:commit-sha":  "e5f9a8b7",
+		"drone-lambda:author":      "appleboy",
+		"drone-lambda:repo":        "override",
+		"drone-lambda:deployed-at": "2024-05-01T12:00:00Z",
+	}
+	if Version != "" {
+		want["drone-lambda:version"] = Version
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deployTags() = %v, want %v", got, want)
+	}
+}
+
+func Test_unqualifiedARN(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:lambda:us-east-1:123456789012:function:api":         "arn:aws:lambda:us-east-1:123456789012:function:api",
+		"arn:aws:lambda:us-east-1:123456789012:function:api:7":       "arn:aws:lambda:us-east-1:123456789012:function:api",
+		"arn:aws:lambda:us-east-1:123456789012:function:api:live":    "arn:aws:lambda:us-east-1:123456789012:function:api",
+		"arn:aws:lambda:us-east-1:123456789012:function:api:$LATEST": "arn:aws:lambda:us-east-1:123456789012:function:api",
+	}
+	for arn, want := range tests {
+		if got := unqualifiedARN(arn); got != want {
+			t.Errorf("unqualifiedARN(%q) = %q, want %q", arn, got, want)
+		}
+	}
+}
+
+func TestPlugin_tagFunction(t *testing.T) {
+	tests := []struct {
+		name      string
+		prune     bool
+		wantStale bool
+	}{
+		{name: "keep stale tags", prune: false, wantStale: true},
+		{name: "prune stale tags", prune: true, wantStale: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeLambda()
+			svc.tags = map[string]string{
+				"owner":                   "ops",
+				"drone-lambda:commit-sha": "old",
+				"drone-lambda:tag":        "v1.0.0",
+				throttleTag:               "20",
+			}
+
+			p := &Plugin{
+				Config: Config{TagsPrune: tt.prune},
+				Commit: Commit{Sha: "new"},
+			}
+			arn := "arn:aws:lambda:us-east-1:123456789012:function:test:3"
+			if err := p.tagFunction(context.Background(), svc, arn); err != nil {
+				t.Fatal(err)
+			}
+
+			if svc.tags["drone-lambda:commit-sha"] != "new" {
+				t.Errorf("commit sha tag = %q, want new", svc.tags["drone-lambda:commit-sha"])
+			}
+			if svc.tags["owner"] != "ops" {
+				t.Errorf("unmanaged tag was removed")
+			}
+			if svc.tags[throttleTag] != "20" {
+				t.Errorf("throttle tag was removed")
+			}
+			if _, ok := svc.tags["drone-lambda:tag"]; ok != tt.wantStale {
+				t.Errorf("stale tag present = %v, want %v", ok, tt.wantStale)
+			}
+		})
+	}
+}
+
+// denyTagsLambda rejects tagging like a deploy role without the
+// lambda:TagResource permission.
+type denyTagsLambda struct {
+	*fakeLambda
+}
+
+func (f denyTagsLambda) TagResourceWithContext(
+	aws.Context, *lambda.TagResourceInput, ...request.Option,
+) (*lambda.TagResourceOutput, error) {
+	return nil, awserr.New("AccessDeniedException", "not authorized to perform lambda:TagResource", nil)
+}
+
+func TestPlugin_deploy_tagFailure(t *testing.T) {
+	svc := denyTagsLambda{newFakeLambda()}
+	p := &Plugin{Config: Config{FunctionName: "test"}}
+
+	err := p.deploy(context.Background(), svc, nil, &lambda.UpdateFunctionCodeInput{
+		FunctionName: aws.String("test"),
+		Publish:      aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("deploy() error = %v, want the tag failure to be a warning", err)
+	}
+	if p.result == nil || p.result.Version != "1" {
+		t.Errorf("result = %+v, want version 1", p.result)
+	}
+}
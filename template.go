@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var templatePattern = regexp.MustCompile(`\$?\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// templateVars returns the build metadata available to ${NAME} references.
+func (p Plugin) templateVars() map[string]string {
+	shortSha := p.Commit.Sha
+	if len(shortSha) > 8 {
+		shortSha = shortSha[:8]
+	}
+
+	return map[string]string{
+		"COMMIT_SHA":       p.Commit.Sha,
+		"COMMIT_SHORT_SHA": shortSha,
+		"COMMIT_AUTHOR":    p.Commit.Author,
+		"COMMIT_BRANCH":    p.Commit.Branch,
+		"COMMIT_TAG":       p.Commit.Tag,
+		"BUILD_NUMBER":     p.Commit.BuildNumber,
+		"REPO":             p.Commit.Repo,
+	}
+}
+
+// expand replaces ${NAME} references in s with build metadata or, as a
+// fallback, environment variables. $${NAME} is kept as a literal ${NAME}.
+func (p Plugin) expand(s string) (string, error) {
+	vars := p.templateVars()
+
+	var missing []string
+	output := templatePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		name := match[2 : len(match)-1]
+		if value, ok := vars[name]; ok && value != "" {
+			return value
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+
+		missing = append(missing, name)
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variable %s", strings.Join(missing, ", "))
+	}
+
+	return output, nil
+}
+
+// expandConfig expands the config values that support ${NAME} references.
+func (p *Plugin) expandConfig() error {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"function-name", &p.Config.FunctionName},
+		{"description", &p.Config.Description},
+		{"s3-key", &p.Config.S3Key},
+		{"image-uri", &p.Config.ImageURI},
+	}
+
+	for _, field := range fields {
+		value, err := p.expand(*field.value)
+		if err != nil {
+			return configError("%s: %w", field.name, err)
+		}
+		*field.value = value
+	}
+
+	return nil
+}
+
+// expandEnvironment expands ${NAME} references in environment values.
+func (p Plugin) expandEnvironment(variables map[string]string) error {
+	for key, value := range variables {
+		value, err := p.expand(value)
+		if err != nil {
+			return configError("environment %s: %w", key, err)
+		}
+		variables[key] = value
+	}
+
+	return nil
+}
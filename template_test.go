@@ -0,0 +1,141 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlugin_expand(t *testing.T) {
+	t.Setenv("DEPLOY_STAGE", "prod")
+
+	p := Plugin{Commit: Commit{
+		Sha:         "e5f9a8b7c6d5e4f3a2b1",
+		Author:      "appleboy",
+		Branch:      "main",
+		BuildNumber: "42",
+	}}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", input: "api", want: "api"},
+		{name: "branch", input: "api-${COMMIT_BRANCH}", want: "api-main"},
+		{
+			name:  "commit metadata",
+			input: "build ${BUILD_NUMBER} from ${COMMIT_SHORT_SHA} by ${COMMIT_AUTHOR}",
+			want:  "build 42 from e5f9a8b7 by appleboy",
+		},
+		{name: "environment fallback", input: "api-${DEPLOY_STAGE}", want: "api-prod"},
+		{name: "escaped", input: "$${COMMIT_BRANCH}", want: "${COMMIT_BRANCH}"},
+		{name: "bare dollar", input: "pa$$word$1", want: "pa$$word$1"},
+		{name: "undefined", input: "api-${UNDEFINED_VARIABLE}", wantErr: true},
+		{name: "empty metadata", input: "api-${COMMIT_TAG}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.expand(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlugin_expandConfig(t *testing.T) {
+	p := Plugin{
+		Config: Config{
+			FunctionName: "api-${COMMIT_BRANCH}",
+			Description:  "commit ${COMMIT_SHA}",
+			S3Key:        "builds/${BUILD_NUMBER}.zip",
+			ImageURI:     "repo/api:${COMMIT_SHORT_SHA}",
+		},
+		Commit: Commit{Sha: "e5f9a8b7c6d5", Branch: "main", BuildNumber: "42"},
+	}
+	if err := p.expandConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Config{
+		FunctionName: "api-main",
+		Description:  "commit e5f9a8b7c6d5",
+		S3Key:        "builds/42.zip",
+		ImageURI:     "repo/api:e5f9a8b7",
+	}
+	if p.Config.FunctionName != want.FunctionName ||
+		p.Config.Description != want.Description ||
+		p.Config.S3Key != want.S3Key ||
+		p.Config.ImageURI != want.ImageURI {
+		t.Errorf("expandConfig() = %+v, want %+v", p.Config, want)
+	}
+}
+
+func TestPlugin_expandEnvironment_dotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "metadata",
+			content: "VERSION=${COMMIT_SHA}\nQUOTED=\"build ${BUILD_NUMBER}\"\nSINGLE='${COMMIT_BRANCH}'\n",
+			want:    map[string]string{"VERSION": "e5f9a8b7", "QUOTED": "build 42", "SINGLE": "main"},
+		},
+		{
+			name:    "literal dollars",
+			content: "ESCAPED=$${COMMIT_SHA}\nPASSWORD=pa$word$1\nBARE=$HOME\n",
+			want:    map[string]string{"ESCAPED": "${COMMIT_SHA}", "PASSWORD": "pa$word$1", "BARE": "$HOME"},
+		},
+		{name: "undefined", content: "C=${UNDEFINED_VARIABLE}y\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Plugin{
+				Config: Config{EnvironmentFile: []string{writeFile(t, "app.env", tt.content)}},
+				Commit: Commit{Sha: "e5f9a8b7", Branch: "main", BuildNumber: "42"},
+			}
+			variables, err := p.environmentVariables()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = p.expandEnvironment(variables)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandEnvironment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(variables, tt.want) {
+				t.Errorf("variables = %v, want %v", variables, tt.want)
+			}
+		})
+	}
+}
+
+func Test_commitTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		refType string
+		refName string
+		want    string
+	}{
+		{name: "drone", tag: "v1.2.0", want: "v1.2.0"},
+		{name: "github tag", refType: "tag", refName: "v1.3.0", want: "v1.3.0"},
+		{name: "github branch", refType: "branch", refName: "main"},
+		{name: "unset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_REF_TYPE", tt.refType)
+			t.Setenv("GITHUB_REF_NAME", tt.refName)
+			if got := commitTag(tt.tag); got != tt.want {
+				t.Errorf("commitTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
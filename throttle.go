@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/urfave/cli/v2"
+)
+
+// throttleTag records the reserved concurrency of a throttled function,
+// so unthrottle can restore it. It is kept when a deploy prunes tags.
+const throttleTag = managedTagPrefix + "throttled-from"
+
+// unreserved is the value of throttleTag for a function that had no
+// reserved concurrency.
+const unreserved = "none"
+
+func throttleCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "throttle",
+		Usage:     "Stop every invocation by setting the reserved concurrency to 0",
+		ArgsUsage: "[function-name...]",
+		Flags:     append([]cli.Flag{functionNamesFlag()}, awsFlags()...),
+		Action: func(c *cli.Context) error {
+			return forEachFunction(c, (*Plugin).throttle)
+		},
+	}
+}
+
+func unthrottleCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "unthrottle",
+		Usage:     "Restore the reserved concurrency saved by throttle",
+		ArgsUsage: "[function-name...]",
+		Flags:     append([]cli.Flag{functionNamesFlag()}, awsFlags()...),
+		Action: func(c *cli.Context) error {
+			return forEachFunction(c, (*Plugin).unthrottle)
+		},
+	}
+}
+
+// functionNamesFlag returns the function name flag of the commands that
+// act on several functions at once.
+func functionNamesFlag() cli.Flag {
+	return &cli.StringSliceFlag{
+		Name:    "function-name",
+		Usage:   "AWS lambda function names, also read from the arguments",
+		EnvVars: []string{"PLUGIN_FUNCTION_NAME", "FUNCTION_NAME", "INPUT_FUNCTION_NAME"},
+	}
+}
+
+// forEachFunction runs fn for every function named by the flag or the
+// arguments, with the credentials and region of the shared flags.
+func forEachFunction(
+	c *cli.Context, fn func(*Plugin, context.Context, lambdaiface.LambdaAPI) error,
+) error {
+	p, err := commandPlugin(c)
+	if err != nil {
+		return err
+	}
+
+	names := append(trimValues(c.StringSlice("function-name")), c.Args().Slice()...)
+	if len(names) == 0 {
+		return configError("missing lambda function name")
+	}
+
+	sess, err := p.newSession()
+	if err != nil {
+		return wrapError("create session", err)
+	}
+
+	return p.forEach(c.Context, lambda.New(sess), names, fn)
+}
+
+// forEach runs fn for each function. A failure does not stop the
+// remaining functions, since the commands are meant for emergencies.
+func (p *Plugin) forEach(
+	ctx context.Context,
+	svc lambdaiface.LambdaAPI,
+	names []string,
+	fn func(*Plugin, context.Context, lambdaiface.LambdaAPI) error,
+) error {
+	var errs []error
+	for _, name := range names {
+		fp := *p
+		fp.Config.FunctionName = name
+		if err := fn(&fp, ctx, svc); err != nil {
+			fp.logger().Error("failed", "error", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// throttle saves the reserved concurrency of the function in a tag and
+// sets it to 0. If the tag exists, the saved value is kept and only the
+// reserved concurrency is set again, since an earlier throttle may have
+// failed after tagging.
+func (p *Plugin) throttle(ctx context.Context, svc lambdaiface.LambdaAPI) error {
+	logger := p.logger().With("phase", "throttle")
+	fn, err := svc.GetFunctionWithContext(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+	})
+	if err != nil {
+		return wrapError("get function", err)
+	}
+
+	previous := unreserved
+	if tag, ok := fn.Tags[throttleTag]; ok {
+		previous = aws.StringValue(tag)
+		logger.Info("function is already tagged as throttled", "previous_reserved", previous)
+	} else {
+		if fn.Concurrency != nil {
+			previous = throttleValue(fn.Concurrency.ReservedConcurrentExecutions)
+		}
+
+		// tag first, so a failed throttle can still be undone
+		if _, err := svc.TagResourceWithContext(ctx, &lambda.TagResourceInput{
+			Resource: aws.String(unqualifiedARN(aws.StringValue(fn.Configuration.FunctionArn))),
+			Tags:     aws.StringMap(map[string]string{throttleTag: previous}),
+		}); err != nil {
+			return wrapError("tag function", err)
+		}
+	}
+	if _, err := svc.PutFunctionConcurrencyWithContext(ctx, &lambda.PutFunctionConcurrencyInput{
+		FunctionName:                 aws.String(p.Config.FunctionName),
+		ReservedConcurrentExecutions: aws.Int64(0),
+	}); err != nil {
+		return wrapError("put function concurrency", err)
+	}
+
+	logger.Info("function throttled", "previous_reserved", previous)
+	return nil
+}
+
+// throttleValue returns the value of throttleTag for reserved.
+func throttleValue(reserved *int64) string {
+	if reserved == nil {
+		return unreserved
+	}
+	return strconv.FormatInt(*reserved, 10)
+}
+
+// saveThrottled replaces the reserved concurrency saved by throttle when
+// the function is throttled, so a deploy does not lift the throttle. It
+// reports whether the function is throttled.
+func (p *Plugin) saveThrottled(ctx context.Context, svc lambdaiface.LambdaAPI, reserved *int64) (bool, error) {
+	fn, err := svc.GetFunctionWithContext(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+	})
+	if err != nil {
+		return false, wrapError("get function", err)
+	}
+	if _, ok := fn.Tags[throttleTag]; !ok {
+		return false, nil
+	}
+
+	if _, err := svc.TagResourceWithContext(ctx, &lambda.TagResourceInput{
+		Resource: aws.String(unqualifiedARN(aws.StringValue(fn.Configuration.FunctionArn))),
+		Tags:     aws.StringMap(map[string]string{throttleTag: throttleValue(reserved)}),
+	}); err != nil {
+		return true, wrapError("tag function", err)
+	}
+	return true, nil
+}
+
+// unthrottle restores the reserved concurrency saved by throttle and
+// removes the tag.
+func (p *Plugin) unthrottle(ctx context.Context, svc lambdaiface.LambdaAPI) error {
+	logger := p.logger().With("phase", "unthrottle")
+	fn, err := svc.GetFunctionWithContext(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(p.Config.FunctionName),
+	})
+	if err != nil {
+		return wrapError("get function", err)
+	}
+
+	tag, ok := fn.Tags[throttleTag]
+	if !ok {
+		logger.Warn("function is not throttled")
+		return nil
+	}
+
+	previous := aws.StringValue(tag)
+	if previous == unreserved {
+		_, err = svc.DeleteFunctionConcurrencyWithContext(ctx, &lambda.DeleteFunctionConcurrencyInput{
+			FunctionName: aws.String(p.Config.FunctionName),
+		})
+		if err != nil {
+			return wrapError("delete function concurrency", err)
+		}
+	} else {
+		reserved, err := strconv.ParseInt(previous, 10, 64)
+		if err != nil {
+			return &Error{
+				Kind: KindInvalidConfig,
+				Op:   "unthrottle",
+				Err:  err,
+				Hint: "fix or remove the " + throttleTag + " tag of the function",
+			}
+		}
+		if _, err := svc.PutFunctionConcurrencyWithContext(ctx, &lambda.PutFunctionConcurrencyInput{
+			FunctionName:                 aws.String(p.Config.FunctionName),
+			ReservedConcurrentExecutions: aws.Int64(reserved),
+		}); err != nil {
+			return wrapError("put function concurrency", err)
+		}
+	}
+
+	if _, err := svc.UntagResourceWithContext(ctx, &lambda.UntagResourceInput{
+		Resource: aws.String(unqualifiedARN(aws.StringValue(fn.Configuration.FunctionArn))),
+		TagKeys:  aws.StringSlice([]string{throttleTag}),
+	}); err != nil {
+		return wrapError("untag function", err)
+	}
+
+	logger.Info("function unthrottled", "reserved", previous)
+	return nil
+}
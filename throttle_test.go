@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+)
+
+// multiLambda routes the calls of several functions to their fakes, by
+// function name or by the name in the function arn.
+type multiLambda struct {
+	lambdaiface.LambdaAPI
+
+	functions map[string]*fakeLambda
+}
+
+func newMultiLambda(reserved map[string]*int64) *multiLambda {
+	m := &multiLambda{functions: map[string]*fakeLambda{}}
+	for name, value := range reserved {
+		f := newFakeLambda()
+		f.config.FunctionName = aws.String(name)
+		f.config.FunctionArn = aws.String("arn:aws:lambda:us-east-1:123456789012:function:" + name)
+		f.reserved = value
+		m.functions[name] = f
+	}
+	return m
+}
+
+func (m *multiLambda) function(name *string) (*fakeLambda, error) {
+	key := aws.StringValue(name)
+	if parts := strings.Split(key, ":"); len(parts) > 6 {
+		key = parts[6]
+	}
+	f, ok := m.functions[key]
+	if !ok {
+		return nil, awserr.New(lambda.ErrCodeResourceNotFoundException, "function not found", nil)
+	}
+	return f, nil
+}
+
+func (m *multiLambda) GetFunctionWithContext(
+	ctx aws.Context, input *lambda.GetFunctionInput, opts ...request.Option,
+) (*lambda.GetFunctionOutput, error) {
+	f, err := m.function(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	return f.GetFunctionWithContext(ctx, input, opts...)
+}
+
+func (m *multiLambda) PutFunctionConcurrencyWithContext(
+	ctx aws.Context, input *lambda.PutFunctionConcurrencyInput, opts ...request.Option,
+) (*lambda.PutFunctionConcurrencyOutput, error) {
+	f, err := m.function(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	return f.PutFunctionConcurrencyWithContext(ctx, input, opts...)
+}
+
+func (m *multiLambda) DeleteFunctionConcurrencyWithContext(
+	ctx aws.Context, input *lambda.DeleteFunctionConcurrencyInput, opts ...request.Option,
+) (*lambda.DeleteFunctionConcurrencyOutput, error) {
+	f, err := m.function(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	return f.DeleteFunctionConcurrencyWithContext(ctx, input, opts...)
+}
+
+func (m *multiLambda) TagResourceWithContext(
+	ctx aws.Context, input *lambda.TagResourceInput, opts ...request.Option,
+) (*lambda.TagResourceOutput, error) {
+	f, err := m.function(input.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return f.TagResourceWithContext(ctx, input, opts...)
+}
+
+func (m *multiLambda) UntagResourceWithContext(
+	ctx aws.Context, input *lambda.UntagResourceInput, opts ...request.Option,
+) (*lambda.UntagResourceOutput, error) {
+	f, err := m.function(input.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return f.UntagResourceWithContext(ctx, input, opts...)
+}
+
+func TestPlugin_throttle(t *testing.T) {
+	svc := newMultiLambda(map[string]*int64{
+		"api":    aws.Int64(20),
+		"worker": nil,
+	})
+	ctx := context.Background()
+	p := &Plugin{}
+	names := []string{"api", "worker"}
+
+	if err := p.forEach(ctx, svc, names, (*Plugin).throttle); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"api": "20", "worker": unreserved} {
+		f := svc.functions[name]
+		if got := aws.Int64Value(f.reserved); f.reserved == nil || got != 0 {
+			t.Errorf("%s: reserved = %v, want 0", name, f.reserved)
+		}
+		if got := f.tags[throttleTag]; got != want {
+			t.Errorf("%s: throttle tag = %q, want %q", name, got, want)
+		}
+	}
+
+	// throttling again keeps the saved value
+	if err := p.forEach(ctx, svc, names, (*Plugin).throttle); err != nil {
+		t.Fatal(err)
+	}
+	if got := svc.functions["api"].tags[throttleTag]; got != "20" {
+		t.Errorf("throttle tag after second throttle = %q, want 20", got)
+	}
+
+	if err := p.forEach(ctx, svc, names, (*Plugin).unthrottle); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]*int64{"api": aws.Int64(20), "worker": nil} {
+		f := svc.functions[name]
+		if !reflect.DeepEqual(f.reserved, want) {
+			t.Errorf("%s: reserved = %v, want %v", name, aws.Int64Value(f.reserved), aws.Int64Value(want))
+		}
+		if _, ok := f.tags[throttleTag]; ok {
+			t.Errorf("%s: throttle tag was not removed", name)
+		}
+	}
+}
+
+// flakyPutLambda fails the first reserved concurrency updates.
+type flakyPutLambda struct {
+	*multiLambda
+
+	failures int
+}
+
+func (f *flakyPutLambda) PutFunctionConcurrencyWithContext(
+	ctx aws.Context, input *lambda.PutFunctionConcurrencyInput, opts ...request.Option,
+) (*lambda.PutFunctionConcurrencyOutput, error) {
+	if f.failures > 0 {
+		f.failures--
+		return nil, awserr.New(lambda.ErrCodeServiceException, "internal error", nil)
+	}
+	return f.multiLambda.PutFunctionConcurrencyWithContext(ctx, input, opts...)
+}
+
+func TestPlugin_throttle_retryAfterFailedPut(t *testing.T) {
+	svc := &flakyPutLambda{multiLambda: newMultiLambda(map[string]*int64{"api": aws.Int64(20)}), failures: 1}
+	f := svc.functions["api"]
+	p := &Plugin{Config: Config{FunctionName: "api"}}
+
+	if err := p.throttle(context.Background(), svc); err == nil {
+		t.Fatal("throttle() expected error")
+	}
+	if got := aws.Int64Value(f.reserved); got != 20 {
+		t.Fatalf("reserved after failed throttle = %d, want 20", got)
+	}
+
+	if err := p.throttle(context.Background(), svc); err != nil {
+		t.Fatal(err)
+	}
+	if f.reserved == nil || *f.reserved != 0 {
+		t.Errorf("reserved = %v, want 0", f.reserved)
+	}
+	if got := f.tags[throttleTag]; got != "20" {
+		t.Errorf("throttle tag = %q, want 20", got)
+	}
+}
+
+func TestPlugin_forEach_failure(t *testing.T) {
+	svc := newMultiLambda(map[string]*int64{"api": nil})
+
+	err := (&Plugin{}).forEach(context.Background(), svc, []string{"missing", "api"}, (*Plugin).throttle)
+	if exitCode(err) != int(KindNotFound) {
+		t.Errorf("exit code = %d, want %d (error %v)", exitCode(err), KindNotFound, err)
+	}
+	// the remaining functions are still throttled
+	if f := svc.functions["api"]; f.reserved == nil || *f.reserved != 0 {
+		t.Errorf("api reserved = %v, want 0", f.reserved)
+	}
+}
+
+func TestPlugin_unthrottle_invalidTag(t *testing.T) {
+	svc := newMultiLambda(map[string]*int64{"api": aws.Int64(0)})
+	svc.functions["api"].tags[throttleTag] = "many"
+
+	err := (&Plugin{}).forEach(context.Background(), svc, []string{"api"}, (*Plugin).unthrottle)
+	if exitCode(err) != int(KindInvalidConfig) {
+		t.Errorf("exit code = %d, want %d (error %v)", exitCode(err), KindInvalidConfig, err)
+	}
+	if got := svc.functions["api"].tags[throttleTag]; got != "many" {
+		t.Errorf("throttle tag = %q, want it kept", got)
+	}
+}
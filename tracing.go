@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	tracerName = "github.com/appleboy/drone-lambda"
+	tracesPath = "/v1/traces"
+)
+
+// newTracerProvider returns a tracer provider exporting to the configured
+// OTLP endpoint, or nil if tracing is not configured.
+func (p Plugin) newTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	if p.Config.OTLPEndpoint == "" {
+		return nil, nil
+	}
+
+	// like OTEL_EXPORTER_OTLP_ENDPOINT, the endpoint is the base URL of
+	// the collector unless it already names the traces path
+	u, err := url.Parse(p.Config.OTLPEndpoint)
+	if err != nil || u.Host == "" {
+		return nil, configError("invalid otlp endpoint %q", p.Config.OTLPEndpoint)
+	}
+	if !strings.HasSuffix(u.Path, tracesPath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + tracesPath
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpointURL(u.String()),
+	}
+	if headers := getEnvironment(trimValues(p.Config.OTLPHeaders)); len(headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, configError("otlp endpoint: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("drone-lambda"),
+			semconv.ServiceVersion(Version),
+		)),
+	), nil
+}
+
+// startSpan starts a span for a deploy phase. Without a tracer the span
+// is a no-op.
+func (p *Plugin) startSpan(
+	ctx context.Context, name string, attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	if p.tracer == nil {
+		p.tracer = noop.NewTracerProvider().Tracer(tracerName)
+	}
+
+	return p.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records the outcome of a span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("deploy.outcome", "failure"))
+	} else {
+		span.SetAttributes(attribute.String("deploy.outcome", "success"))
+	}
+	span.End()
+}
+
+// traceAttributes are set on the root span of the deploy.
+func (p Plugin) traceAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.CloudProviderAWS,
+		semconv.CloudRegion(p.Config.Region),
+		semconv.FaaSName(p.Config.FunctionName),
+		attribute.Bool("lambda.dry_run", p.Config.DryRun),
+		attribute.String("vcs.commit.sha", p.Commit.Sha),
+	}
+}
+
+// shutdownTracer flushes the pending spans.
+func shutdownTracer(ctx context.Context, tp *sdktrace.TracerProvider) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	return tp.Shutdown(ctx)
+}
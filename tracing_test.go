@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collectorStub records the spans exported to it over OTLP/HTTP.
+type collectorStub struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	spans map[string]*tracepb.Span
+}
+
+func newCollectorStub(t *testing.T) *collectorStub {
+	t.Helper()
+
+	stub := &collectorStub{spans: make(map[string]*tracepb.Span)}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tracesPath {
+			t.Errorf("path = %q, want %q", r.URL.Path, tracesPath)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					stub.spans[span.Name] = span
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		out, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		_, _ = w.Write(out)
+	}))
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+func spanAttribute(span *tracepb.Span, key string) string {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestPlugin_deploy_tracing(t *testing.T) {
+	collector := newCollectorStub(t)
+
+	p := &Plugin{
+		Config: Config{
+			FunctionName: "api",
+			Region:       "eu-west-1",
+			SkipTags:     true,
+			OTLPEndpoint: collector.URL,
+			OTLPHeaders:  []string{"Authorization=Bearer secret"},
+		},
+	}
+	ctx := context.Background()
+	tp, err := p.newTracerProvider(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.tracer = tp.Tracer(tracerName)
+
+	ctx, span := p.startSpan(ctx, "deploy", p.traceAttributes()...)
+	cfg := &lambda.UpdateFunctionConfigurationInput{FunctionName: aws.String("api")}
+	cfg.SetMemorySize(256)
+	input := &lambda.UpdateFunctionCodeInput{FunctionName: aws.String("api")}
+	input.SetZipFile([]byte("zip"))
+	input.SetPublish(true)
+	err = p.deploy(ctx, newFakeLambda(), cfg, input)
+	endSpan(span, err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdownTracer(ctx, tp); err != nil {
+		t.Fatal(err)
+	}
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	for _, name := range []string{
+		"deploy", "check_status", "update_function_configuration", "update_function_code",
+	} {
+		if _, ok := collector.spans[name]; !ok {
+			t.Errorf("missing span %q", name)
+		}
+	}
+
+	root := collector.spans["deploy"]
+	if root == nil {
+		t.FailNow()
+	}
+	for key, want := range map[string]string{
+		"faas.name":      "api",
+		"cloud.region":   "eu-west-1",
+		"deploy.outcome": "success",
+	} {
+		if got := spanAttribute(root, key); got != want {
+			t.Errorf("deploy %s = %q, want %q", key, got, want)
+		}
+	}
+
+	code := collector.spans["update_function_code"]
+	if got := spanAttribute(code, "lambda.version"); got != "1" {
+		t.Errorf("lambda.version = %q, want 1", got)
+	}
+	if len(code.Events) != 1 || code.Events[0].Name != "version published" {
+		t.Errorf("events = %v, want version published", code.Events)
+	}
+	if code.ParentSpanId == nil || string(code.ParentSpanId) != string(root.SpanId) {
+		t.Error("update_function_code is not a child of deploy")
+	}
+}